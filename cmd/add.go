@@ -5,36 +5,144 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/dates"
 	"github.com/spf13/cobra"
 )
 
 // addCmd represents the add command
-func newAddCmd(storage *file.FileStorage) *cobra.Command {
-	return &cobra.Command{
+func newAddCmd(storage *file.Repository) *cobra.Command {
+	var (
+		due, priority, project, recur, parentID string
+		tags                                    []string
+	)
+	addCmd := &cobra.Command{
 		Use:   "add",
 		Short: "add a new task",
 		Long: `add a new task
-tasks add <task description> to add a new task`,
+tasks add <task description> to add a new task
+tasks add <task description> --due <date> to add a task with a due date
+tasks add <task description> --priority H to add a task with a high priority (H, M or L)
+tasks add <task description> +tag project:name to add a task with a tag and a project
+tasks add <task description> --recur weekly --due fri to add a task that repeats every week
+tasks add <task description> --parent <task ID> to add a subtask of another task
+tasks add <task description> depends:3,4 to add a task that is blocked until tasks 3 and 4 are completed
+
+Tags, projects and dependencies are arguments of their own, a quoted description
+is kept as it is: tasks add "Call +1 555" +phone has the tag phone only.
+
+Due dates can be given as tomorrow, fri, "next fri 17:00", +3d or 2025-06-15.
+
+Tasks can recur daily, weekdays, weekly, monthly, yearly, "every 2w" (d, w, mo
+or y) or following an RRULE like "FREQ=WEEKLY;BYDAY=MO,TH". Completing a
+recurring task adds the next one, deleting its template (tasks list
+status:recurring) stops it.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if len(args) < 1 {
+			attrs, err := parseAttributes(*storage, args)
+			if err != nil {
+				fmt.Fprintf(cmd.OutOrStderr(), "Invalid task: %v\n", err)
+				return
+			}
+			if len(attrs.words) == 0 {
 				fmt.Fprintln(cmd.OutOrStdout(), "Please provide a task description.")
 				return
 			}
+			if len(attrs.removeDepends) > 0 {
+				fmt.Fprintf(cmd.OutOrStderr(), "Cannot remove dependencies from a new task: %v\n", attrs.removeDepends)
+				return
+			}
+			if len(attrs.removeTags) > 0 {
+				fmt.Fprintf(cmd.OutOrStderr(), "Cannot remove tags from a new task: -%s\n", strings.Join(attrs.removeTags, " -"))
+				return
+			}
 
-			// TODO: Manage task description with spaces
-			if len(args) > 1 {
-				fmt.Fprintf(cmd.OutOrStdout(), "Please provide the task description surrounded by quotes\n\nExample: task add \"Go to the gym\"\n")
+			taskPriority, err := file.ParsePriority(priority)
+			if err != nil {
+				fmt.Fprintf(cmd.OutOrStderr(), "Invalid priority: %v\n", err)
+				return
+			}
+			task := file.Task{
+				Task:     strings.Join(attrs.words, " "),
+				Priority: taskPriority,
+				Tags:     file.NormalizeTags(append(attrs.addTags, tags...)),
+				Project:  attrs.project,
+			}
+			if project != "" {
+				task.Project = project
+			}
+			for _, tag := range task.Tags {
+				if err := file.ValidateTag(tag); err != nil {
+					fmt.Fprintf(cmd.OutOrStderr(), "Invalid tag: %v\n", err)
+					return
+				}
+			}
+			if err := file.ValidateProject(task.Project); err != nil {
+				fmt.Fprintf(cmd.OutOrStderr(), "Invalid project: %v\n", err)
 				return
 			}
+			if due != "" {
+				dueAt, err := dates.Parse(due, time.Now())
+				if err != nil {
+					fmt.Fprintf(cmd.OutOrStderr(), "Invalid due date: %v\n", err)
+					return
+				}
+				task.Due = dueAt.UTC()
+			}
 
-			task := args[0]
-			if err := (*storage).AddTask(task); err != nil {
+			var parent int
+			if parentID != "" {
+				if parent, err = parseTaskID(*storage, parentID); err != nil {
+					fmt.Fprintf(cmd.OutOrStderr(), "Invalid parent: %v\n", err)
+					return
+				}
+			}
+			if err := file.ValidateParent(*storage, 0, parent); err != nil {
 				fmt.Fprintf(cmd.OutOrStderr(), "Error adding task: %v\n", err)
 				return
 			}
-			fmt.Fprintf(cmd.OutOrStdout(), "Task added successfully: %s\n", task)
+			task.Parent = parent
+
+			task.Depends = file.NormalizeIDs(attrs.addDepends)
+			if err := file.ValidateDependencies(*storage, 0, task.Depends); err != nil {
+				fmt.Fprintf(cmd.OutOrStderr(), "Error adding task: %v\n", err)
+				return
+			}
+
+			if recur != "" {
+				rule, err := file.ParseRecurrence(recur)
+				if err != nil {
+					fmt.Fprintf(cmd.OutOrStderr(), "Invalid recurrence: %v\n", err)
+					return
+				}
+				// The template is added first, the task is its first instance
+				template := task
+				template.Recur = rule.String()
+				template, err = (*storage).Create(template)
+				if err != nil {
+					fmt.Fprintf(cmd.OutOrStderr(), "Error adding task: %v\n", err)
+					return
+				}
+				task.Recur = template.Recur
+				task.RecurParent = template.ID
+			}
+
+			task, err = (*storage).Create(task)
+			if err != nil {
+				fmt.Fprintf(cmd.OutOrStderr(), "Error adding task: %v\n", err)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Task added successfully: %s\n", task.Task)
 		},
 	}
+
+	addCmd.Flags().StringVar(&due, "due", "", "due date of the task")
+	addCmd.Flags().StringVarP(&priority, "priority", "p", "", "priority of the task (H, M or L)")
+	addCmd.Flags().StringSliceVarP(&tags, "tag", "t", nil, "tags of the task, can be repeated")
+	addCmd.Flags().StringVar(&project, "project", "", "project of the task")
+	addCmd.Flags().StringVar(&parentID, "parent", "", "ID of the task this one is a subtask of")
+	addCmd.Flags().StringVar(&recur, "recur", "", "how often the task repeats (daily, weekdays, weekly, monthly, yearly, every 2w or an RRULE)")
+	return addCmd
 }
@@ -0,0 +1,100 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/spf13/cobra"
+)
+
+// Write a note for task in the user's editor. Lines starting with # are
+// dropped, like in git commit messages
+func editAnnotation(cmd *cobra.Command, task file.Task) (string, error) {
+	tmp, err := os.CreateTemp("", fmt.Sprintf("task-%d-note-*.txt", task.ID))
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	header := fmt.Sprintf("\n# Write a note for task %d: %s\n# Lines starting with # are ignored, an empty note cancels.\n", task.ID, task.Task)
+	if _, err := tmp.WriteString(header); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("error writing temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("error writing temporary file: %w", err)
+	}
+
+	if err := runEditor(cmd, tmp.Name()); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return "", fmt.Errorf("error reading temporary file: %w", err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		if !strings.HasPrefix(scanner.Text(), "#") {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	return strings.Join(lines, "\n"), scanner.Err()
+}
+
+// annotateCmd represents the annotate command
+func newAnnotateCmd(storage *file.Repository) *cobra.Command {
+	return &cobra.Command{
+		Use:   "annotate",
+		Short: "add a note to a task",
+		Long: `add a timestamped note to a task
+tasks annotate <task ID> "called the vendor, waiting for a quote"
+tasks annotate <task ID> to write a longer note in $EDITOR
+tasks show <task ID> to read the notes of a task`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("please provide the ID of the task to annotate")
+			}
+			taskID, err := parseTaskID(*storage, args[0])
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+
+			task, err := (*storage).Get(taskID)
+			if err != nil {
+				return fmt.Errorf("error annotating task: %w", err)
+			}
+			if task.Deleted() {
+				return deletedError(task)
+			}
+
+			text := strings.Join(args[1:], " ")
+			if text == "" {
+				if text, err = editAnnotation(cmd, task); err != nil {
+					return err
+				}
+			}
+			if err := task.Annotate(text, time.Now()); errors.Is(err, file.ErrEmptyAnnotation) {
+				fmt.Fprintln(cmd.OutOrStdout(), "Empty note, nothing added")
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			if _, err := (*storage).Update(task); err != nil {
+				return fmt.Errorf("error annotating task: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Annotated task %d: %s\n", task.ID, task.Task)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
+	"github.com/MoXcz/tasks/internal/dates"
+	"github.com/MoXcz/tasks/internal/filter"
+	"github.com/spf13/cobra"
+)
+
+// Archived tasks matching the filter expression in args, from every archive. A
+// filter with a single ID fails when no archive has the task
+func selectArchived(cfg *config.Config, args []string) ([]file.Task, error) {
+	f, err := filter.Parse(args, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	paths, err := file.ArchivePaths(cfg.Filepath, cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []file.Task
+	for _, path := range paths {
+		archive, err := file.SelectStorage(path, cfg.Storage)
+		if err != nil {
+			return nil, err
+		}
+		selected, err := archive.List(file.Filter{All: true, Where: f.Match})
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		tasks = append(tasks, selected...)
+	}
+	if id, ok := f.ID(); ok && len(tasks) == 0 {
+		return nil, &file.NotFoundError{ID: id}
+	}
+	return tasks, nil
+}
+
+// archiveCmd represents the archive command
+func newArchiveCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	var olderThan string
+	archiveCmd := &cobra.Command{
+		Use:     "archive",
+		Aliases: []string{"clean"},
+		Short:   "move completed tasks out of the tasks file",
+		Long: `move completed and cancelled tasks to an archive, so the tasks file stays small
+tasks archive to archive every completed and cancelled task
+tasks archive --older-than 30d to only archive the tasks closed more than 30 days ago
+tasks list --archived to list the archived tasks, it takes filters like tasks list
+
+Archives are kept next to the tasks file in the same format, one per day
+tasks are archived on, like tasks.archive-2025-06-18.csv. Archived tasks keep
+their IDs, which are never given to new tasks. A task with subtasks is only
+archived along with all of them, and deleted tasks stay in the trash.
+tasks undo takes the tasks out of the archive again.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("archive takes no arguments, see --older-than")
+			}
+			now := time.Now()
+			var before time.Time
+			if olderThan != "" {
+				var err error
+				if before, err = dates.Since(olderThan, now); err != nil {
+					return fmt.Errorf("invalid --older-than: %w", err)
+				}
+			}
+			cmd.SilenceUsage = true
+
+			path := file.ArchivePath(cfg.Filepath, now)
+			archived, err := file.Archive(*storage, path, cfg.Storage, before)
+			if err != nil {
+				return fmt.Errorf("error archiving tasks: %w", err)
+			}
+			if len(archived) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "Nothing to archive")
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Archived %d tasks to %s.%s\n", len(archived), path, cfg.Storage)
+			return nil
+		},
+	}
+
+	archiveCmd.Flags().StringVar(&olderThan, "older-than", "", "only the tasks closed before this date or this long ago, like 30d")
+	return archiveCmd
+}
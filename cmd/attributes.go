@@ -0,0 +1,82 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/MoXcz/tasks/file"
+)
+
+// Attributes given as arguments of their own next to a description:
+// +tag, -tag, project:name and depends:1,2 (or depends:-1 to drop a dependency).
+// Dependencies can also be given by the start of their UUID. An argument of
+// several words is always text, so a quoted description like "Call +1 555" is
+// kept as it is
+type attributes struct {
+	// Every argument that is not an attribute, as given
+	words         []string
+	addTags       []string
+	removeTags    []string
+	project       string
+	hasProject    bool
+	addDepends    []int
+	removeDepends []int
+	// An empty depends: removes every dependency
+	clearDepends bool
+}
+
+func parseAttributes(storage file.Repository, args []string) (attributes, error) {
+	var attrs attributes
+	for _, word := range args {
+		switch {
+		case strings.TrimSpace(word) == "":
+			continue
+		case strings.ContainsFunc(word, unicode.IsSpace):
+			attrs.words = append(attrs.words, word)
+		case len(word) > 1 && word[0] == '+':
+			tag := word[1:]
+			if err := file.ValidateTag(tag); err != nil {
+				return attributes{}, err
+			}
+			attrs.addTags = append(attrs.addTags, tag)
+		case len(word) > 1 && word[0] == '-':
+			tag := word[1:]
+			if err := file.ValidateTag(tag); err != nil {
+				return attributes{}, err
+			}
+			attrs.removeTags = append(attrs.removeTags, tag)
+		case strings.HasPrefix(word, "depends:"):
+			value := strings.TrimPrefix(word, "depends:")
+			if value == "" {
+				attrs.clearDepends = true
+				continue
+			}
+			for id := range strings.SplitSeq(value, ",") {
+				remove := strings.HasPrefix(id, "-")
+				depID, err := parseTaskID(storage, strings.TrimPrefix(id, "-"))
+				if errors.Is(err, file.ErrNotFound) {
+					return attributes{}, fmt.Errorf("invalid dependency %q: %w", id, err)
+				}
+				if err != nil || depID <= 0 {
+					return attributes{}, fmt.Errorf("invalid dependency %q, use depends:<task ID>", id)
+				}
+				if remove {
+					attrs.removeDepends = append(attrs.removeDepends, depID)
+				} else {
+					attrs.addDepends = append(attrs.addDepends, depID)
+				}
+			}
+		case strings.HasPrefix(word, "project:"):
+			attrs.project = strings.TrimPrefix(word, "project:")
+			attrs.hasProject = true
+		default:
+			attrs.words = append(attrs.words, word)
+		}
+	}
+	return attrs, nil
+}
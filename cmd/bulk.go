@@ -0,0 +1,98 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
+	"github.com/MoXcz/tasks/internal/filter"
+	"github.com/spf13/cobra"
+)
+
+// Tasks matching the filter expression in args, see the filter package. Only
+// pending tasks are considered unless all is set or the filter names IDs or a
+// status. A filter with a single ID fails when the task does not exist or is
+// deleted
+func selectTasks(storage file.Repository, args []string, all bool) ([]file.Task, error) {
+	f, err := filter.Parse(args, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := storage.List(file.Filter{All: all || f.IncludesCompleted(), Where: f.Match})
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := f.ID(); ok && len(tasks) == 0 {
+		if task, err := storage.Get(id); err == nil && task.Deleted() {
+			return nil, deletedError(task)
+		}
+		return nil, &file.NotFoundError{ID: id}
+	}
+	return tasks, nil
+}
+
+// The ID of a task given on the command line either by its ID or by the start
+// of its UUID, at least file.MinUUIDPrefix characters of it
+func parseTaskID(storage file.Repository, s string) (int, error) {
+	if id, err := strconv.Atoi(s); err == nil {
+		// A UUID can start with digits alone, it is only taken for one when
+		// there is no task with that ID
+		if !file.IsUUIDPrefix(s) {
+			return id, nil
+		}
+		if _, err := storage.Get(id); !errors.Is(err, file.ErrNotFound) {
+			return id, nil
+		}
+		if task, err := file.FindByUUID(storage, s); err == nil {
+			return task.ID, nil
+		}
+		return id, nil
+	}
+	if !file.IsUUIDPrefix(s) {
+		return 0, fmt.Errorf("invalid task ID: %s", s)
+	}
+	task, err := file.FindByUUID(storage, s)
+	if err != nil {
+		return 0, err
+	}
+	return task.ID, nil
+}
+
+// Pending dependencies of every blocked task among the pending ones
+func blockingTasks(storage file.Repository) (map[int][]int, error) {
+	pending, err := storage.List(file.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	blocking := map[int][]int{}
+	for _, task := range pending {
+		if deps := file.BlockingTasks(pending, task); len(deps) > 0 {
+			blocking[task.ID] = deps
+		}
+	}
+	return blocking, nil
+}
+
+// Number of tasks a command can change at once without asking for confirmation
+func confirmThreshold(cfg *config.Config) int {
+	if cfg.ConfirmThreshold <= 0 {
+		return config.DefaultConfirmThreshold
+	}
+	return cfg.ConfirmThreshold
+}
+
+// Show the tasks a bulk change is about to touch and ask to go ahead. action
+// is the verb of the question, like delete
+func confirmBulk(cmd *cobra.Command, action string, tasks []file.Task) bool {
+	if err := printTasks(cmd.OutOrStdout(), tasks, printContext{}); err != nil {
+		return false
+	}
+	return confirm(cmd.InOrStdin(), cmd.OutOrStdout(), fmt.Sprintf("Are you sure you want to %s these %d tasks", action, len(tasks)))
+}
@@ -5,36 +5,112 @@ package cmd
 
 import (
 	"fmt"
-	"strconv"
+	"slices"
+	"time"
 
 	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
 	"github.com/spf13/cobra"
 )
 
 // completeCmd represents the complete command
-func newCompleteCmd(storage *file.FileStorage) *cobra.Command {
-	return &cobra.Command{
+func newCompleteCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	completeCmd := &cobra.Command{
 		Use:   "complete",
 		Short: "complete a task",
 		Long: `complete a task
-tasks complete <task ID> to mark a task as completed`,
+tasks complete <task ID> to mark a task as completed
+tasks complete <filter> to complete every pending task matching a filter, like
+  tasks complete 1,3,5-9
+  tasks complete +errand project:home
+
+Completing a task with pending subtasks asks to complete them too, and
+completing one that depends on pending tasks prints a warning. Completing more
+tasks than confirm_threshold (3 by default) at once asks for confirmation
+first, --force skips both. See tasks list --help for filters.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if len(args) != 1 {
-				fmt.Fprintln(cmd.OutOrStdout(), "Please provide exactly one task ID to complete.")
+			if len(args) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "Please provide the ID of the task to complete or a filter.")
 				return
 			}
 
-			ID := args[0]
-			taskID, err := strconv.Atoi(ID)
+			tasks, err := selectTasks(*storage, args, false)
 			if err != nil {
-				fmt.Fprintf(cmd.OutOrStderr(), "Invalid task ID: %s. Please provide a valid integer ID.\n", ID)
+				fmt.Fprintf(cmd.OutOrStderr(), "Error completing task: %v\n", err)
+				return
+			}
+			if len(tasks) == 0 {
+				fmt.Fprintln(cmd.OutOrStderr(), "No matching tasks")
 				return
 			}
 
-			if err := (*storage).CompleteTask(cmd.OutOrStderr(), taskID); err != nil {
+			force, _ := cmd.Flags().GetBool("force")
+			if !force && len(tasks) > confirmThreshold(cfg) && !confirmBulk(cmd, "complete", tasks) {
+				return
+			}
+
+			pending, err := (*storage).List(file.Filter{})
+			if err != nil {
 				fmt.Fprintf(cmd.OutOrStderr(), "Error completing task: %v\n", err)
 				return
 			}
+
+			completed := map[int]bool{}
+			complete := func(task file.Task) bool {
+				fmt.Fprintln(cmd.OutOrStderr(), "Completing task:", task.Task)
+				_, next, err := file.CompleteTask(*storage, task.ID, time.Now())
+				if err != nil {
+					fmt.Fprintf(cmd.OutOrStderr(), "Error completing task: %v\n", err)
+					return false
+				}
+				completed[task.ID] = true
+				if next.ID != 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "Next occurrence: task %d due %s\n", next.ID, next.Due.Local().Format("Mon 2006-01-02 15:04"))
+				}
+				return true
+			}
+
+			for _, task := range tasks {
+				if completed[task.ID] {
+					// Already completed as a subtask of another selected task
+					continue
+				}
+				if task.Closed() {
+					fmt.Fprintf(cmd.OutOrStderr(), "Error completing task: task with ID %d is already %s\n", task.ID, task.Status)
+					continue
+				}
+				if task.IsTemplate() {
+					fmt.Fprintf(cmd.OutOrStderr(), "Error completing task: task with ID %d is a recurring task template, delete it to stop the recurrence\n", task.ID)
+					continue
+				}
+
+				// A task is only done once all of its subtasks are
+				subtasks := slices.DeleteFunc(file.Descendants(pending, task.ID), func(t file.Task) bool { return completed[t.ID] })
+				if len(subtasks) > 0 {
+					question := fmt.Sprintf("Task %d has %d pending subtasks, complete them too", task.ID, len(subtasks))
+					if !force && !confirm(cmd.InOrStdin(), cmd.OutOrStdout(), question) {
+						fmt.Fprintf(cmd.OutOrStderr(), "Not completing task %d, its subtasks are still pending\n", task.ID)
+						continue
+					}
+					// Deepest first, so no task is ever done before its subtasks
+					for _, subtask := range slices.Backward(subtasks) {
+						if !complete(subtask) {
+							return
+						}
+					}
+				}
+
+				blocking := slices.DeleteFunc(file.BlockingTasks(pending, task), func(id int) bool { return completed[id] })
+				if len(blocking) > 0 {
+					fmt.Fprintf(cmd.OutOrStderr(), "Warning: task %d is blocked by pending tasks %s\n", task.ID, joinIDs(blocking))
+				}
+				if !complete(task) {
+					return
+				}
+			}
 		},
 	}
+
+	completeCmd.Flags().BoolP("force", "f", false, "Skip confirmation")
+	return completeCmd
 }
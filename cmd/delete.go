@@ -6,41 +6,140 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"strconv"
+	"slices"
+	"time"
 
 	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 // deleteCmd represents the delete command
-func newDeleteCmd(storage *file.FileStorage) *cobra.Command {
+func newDeleteCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	var subtasks string
 	var deleteCmd = &cobra.Command{
 		Use:   "delete",
-		Short: "delete a task",
-		Long: `delete a task
-tasks delete <task ID> to delete a task from the list`,
+		Short: "move a task to the trash",
+		Long: `move a task to the trash, leaving it out of every list
+tasks delete <task ID> to delete a task from the list
+tasks delete <filter> to delete every task matching a filter, like
+  tasks delete 1,3,5-9
+  tasks delete status:completed project:old
+
+Deleted tasks can be seen with tasks trash and brought back with tasks restore
+until tasks purge removes them for good.
+
+The subtasks of a deleted task move up to its parent, or to the top level,
+unless --subtasks delete is given to delete them too.
+
+Deleting an uncompleted task or more tasks than confirm_threshold (3 by
+default) at once asks for confirmation first, --force skips it. See tasks
+list --help for filters.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if len(args) != 1 {
-				fmt.Println("Please provide exactly one task ID to delete.")
+			if len(args) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "Please provide the ID of the task to delete or a filter.")
+				return
+			}
+			if subtasks != "detach" && subtasks != "delete" {
+				fmt.Fprintf(cmd.OutOrStderr(), "Invalid --subtasks value: %s. Please use detach or delete.\n", subtasks)
 				return
 			}
 
-			ID := args[0]
-			taskID, err := strconv.Atoi(ID)
+			tasks, err := selectTasks(*storage, args, false)
 			if err != nil {
-				fmt.Fprintf(cmd.OutOrStderr(), "Invalid task ID: %s.\n", ID)
+				fmt.Fprintf(cmd.OutOrStderr(), "Error deleting task: %v\n", err)
+				return
+			}
+			if len(tasks) == 0 {
+				fmt.Fprintln(cmd.OutOrStderr(), "No matching tasks")
 				return
 			}
 
-			if err := (*storage).DeleteTask(cmd.OutOrStdout(), taskID); err != nil {
+			force := viper.GetBool("force")
+			if !force && len(tasks) > confirmThreshold(cfg) {
+				if !confirmBulk(cmd, "delete", tasks) {
+					return
+				}
+				force = true
+			}
+
+			// Kept up to date as subtasks are moved, so a task deleted after its
+			// parent moves its own subtasks to the right place
+			all, err := (*storage).List(file.Filter{All: true})
+			if err != nil {
 				fmt.Fprintf(cmd.OutOrStderr(), "Error deleting task: %v\n", err)
 				return
 			}
+			current := func(id int) *file.Task {
+				i := slices.IndexFunc(all, func(t file.Task) bool { return t.ID == id })
+				if i < 0 {
+					return nil
+				}
+				return &all[i]
+			}
+			now := time.Now()
+			remove := func(task file.Task, label string) bool {
+				fmt.Fprintf(cmd.OutOrStdout(), "Deleting %s: %s\n", label, task.Task)
+				task.DeletedAt = now
+				if _, err := (*storage).Update(task); err != nil {
+					fmt.Fprintf(cmd.OutOrStderr(), "Error deleting task: %v\n", err)
+					return false
+				}
+				all = slices.DeleteFunc(all, func(t file.Task) bool { return t.ID == task.ID })
+				return true
+			}
+
+			for _, task := range tasks {
+				latest := current(task.ID)
+				if latest == nil {
+					// Already deleted as a subtask of another selected task
+					continue
+				}
+				task = *latest
+
+				question := "Are you sure you want to delete this uncompleted task"
+				if len(tasks) > 1 {
+					question = fmt.Sprintf("Are you sure you want to delete the uncompleted task %d (%s)", task.ID, task.Task)
+				}
+				if !task.Closed() && !force && !confirm(cmd.InOrStdin(), cmd.OutOrStdout(), question) {
+					continue
+				}
+
+				if subtasks == "delete" {
+					// Deepest first, so no subtask is ever left without its parent
+					for _, subtask := range slices.Backward(file.Descendants(all, task.ID)) {
+						if !remove(subtask, "subtask") {
+							return
+						}
+					}
+				}
+				for i := range all {
+					child := &all[i]
+					if child.Parent != task.ID {
+						continue
+					}
+					child.Parent = task.Parent
+					if _, err := (*storage).Update(*child); err != nil {
+						fmt.Fprintf(cmd.OutOrStderr(), "Error moving subtask %d: %v\n", child.ID, err)
+						return
+					}
+					if task.Parent == 0 {
+						fmt.Fprintf(cmd.OutOrStdout(), "Moved subtask %d to the top level\n", child.ID)
+					} else {
+						fmt.Fprintf(cmd.OutOrStdout(), "Moved subtask %d under task %d\n", child.ID, task.Parent)
+					}
+				}
+
+				if !remove(task, "task") {
+					return
+				}
+			}
 		},
 	}
 
 	deleteCmd.Flags().BoolP("force", "f", false, "Skip confirmation")
+	deleteCmd.Flags().StringVar(&subtasks, "subtasks", "detach", "what to do with the subtasks of deleted tasks: detach (move them up) or delete")
 	if err := viper.BindPFlag("force", deleteCmd.Flags().Lookup("force")); err != nil {
 		fmt.Fprintf(os.Stderr, "error binding --force flag: %v\n", err)
 	}
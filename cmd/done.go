@@ -0,0 +1,56 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/dates"
+	"github.com/spf13/cobra"
+)
+
+// doneCmd represents the done command
+func newDoneCmd(storage *file.Repository) *cobra.Command {
+	var since string
+	doneCmd := &cobra.Command{
+		Use:   "done",
+		Short: "list the tasks completed lately",
+		Long: `list the tasks completed since a date, the earliest first
+tasks done --since 7d to see what was completed in the last week
+tasks done --since mon to see what was completed since monday
+tasks done <filter> to only look at the tasks matching a filter, like
+  tasks done --since 2w project:work`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			from, err := dates.Since(since, now)
+			if err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+
+			tasks, err := selectTasks(*storage, args, true)
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
+			}
+			cmd.SilenceUsage = true
+
+			tasks = slices.DeleteFunc(tasks, func(task file.Task) bool {
+				return !task.IsComplete() || task.CompletedAt.Before(from)
+			})
+			if len(tasks) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No tasks completed since", from.Format(completedLayout))
+				return nil
+			}
+			slices.SortStableFunc(tasks, func(a, b file.Task) int { return a.CompletedAt.Compare(b.CompletedAt) })
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Completed %d tasks since %s\n", len(tasks), from.Format(completedLayout))
+			return printTasks(cmd.OutOrStdout(), tasks, printContext{timestamps: true})
+		},
+	}
+
+	doneCmd.Flags().StringVar(&since, "since", "7d", "list the tasks completed after this date or this long ago, like 7d or mon")
+	return doneCmd
+}
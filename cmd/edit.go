@@ -0,0 +1,254 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/dates"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Fields of a task that can be changed with tasks edit. The status is not one
+// of them, the status commands take care of what comes with a change, like
+// the next instance of a recurring task or the time tracked on it
+type editableTask struct {
+	Task     string   `yaml:"task"`
+	Priority string   `yaml:"priority"`
+	Project  string   `yaml:"project"`
+	Tags     []string `yaml:"tags"`
+	Due      string   `yaml:"due"`
+	Parent   int      `yaml:"parent"`
+	Depends  []int    `yaml:"depends"`
+}
+
+const editDueLayout = "2006-01-02 15:04"
+
+func renderEditable(task file.Task) ([]byte, error) {
+	e := editableTask{
+		Task:     task.Task,
+		Priority: string(task.Priority),
+		Project:  task.Project,
+		Tags:     task.Tags,
+		Parent:   task.Parent,
+		Depends:  task.Depends,
+	}
+	if !task.Due.IsZero() {
+		e.Due = task.Due.Local().Format(editDueLayout)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Editing task %d, created %s\n", task.ID, task.CreatedAt.Local().Format(time.RFC1123))
+	fmt.Fprintln(&buf, "# Save and quit to apply the changes, empty the task description to cancel.")
+	fmt.Fprintln(&buf, "# priority: H, M, L or empty. due: any date accepted by --due, like tomorrow or +3d")
+	fmt.Fprintln(&buf, "# parent: ID of the task this one is a subtask of, 0 for none. depends: IDs like [3, 4]")
+	fmt.Fprintf(&buf, "# The task is %s, tasks start, complete, wait, block, cancel and reopen change that\n", task.StatusAt(time.Now()))
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(e); err != nil {
+		return nil, fmt.Errorf("error rendering task: %w", err)
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// Apply an edited rendering to task. It returns false when the user cancelled
+// the edit by emptying the description
+func applyEditable(task file.Task, data []byte) (file.Task, bool, error) {
+	var e editableTask
+	// Unknown fields are an error rather than a change that is silently lost
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&e); err != nil && !errors.Is(err, io.EOF) {
+		return file.Task{}, false, fmt.Errorf("invalid YAML: %w", err)
+	}
+	e.Task = strings.TrimSpace(e.Task)
+	if e.Task == "" {
+		return file.Task{}, false, nil
+	}
+
+	priority, err := file.ParsePriority(e.Priority)
+	if err != nil {
+		return file.Task{}, false, err
+	}
+	for _, tag := range e.Tags {
+		if err := file.ValidateTag(tag); err != nil {
+			return file.Task{}, false, err
+		}
+	}
+	if err := file.ValidateProject(e.Project); err != nil {
+		return file.Task{}, false, err
+	}
+
+	// An untouched due date keeps its seconds, which the rendering drops
+	e.Due = strings.TrimSpace(e.Due)
+	switch {
+	case e.Due == "":
+		task.Due = time.Time{}
+	case task.Due.IsZero() || e.Due != task.Due.Local().Format(editDueLayout):
+		due, err := dates.Parse(e.Due, time.Now())
+		if err != nil {
+			return file.Task{}, false, err
+		}
+		task.Due = due.UTC()
+	}
+
+	if e.Parent < 0 || slices.ContainsFunc(e.Depends, func(id int) bool { return id <= 0 }) {
+		return file.Task{}, false, fmt.Errorf("task IDs are positive numbers")
+	}
+
+	task.Task = e.Task
+	task.Priority = priority
+	task.Project = e.Project
+	task.Tags = file.NormalizeTags(e.Tags)
+	task.Parent = e.Parent
+	task.Depends = file.NormalizeIDs(e.Depends)
+	return task, true, nil
+}
+
+// Check the parent and the new dependencies of an edited task, like tasks
+// modify does. Dependencies it already had are left alone
+func validateEdited(storage file.Repository, task, edited file.Task) error {
+	if edited.Parent != task.Parent {
+		if err := file.ValidateParent(storage, task.ID, edited.Parent); err != nil {
+			return err
+		}
+	}
+	added := slices.DeleteFunc(slices.Clone(edited.Depends), func(id int) bool { return slices.Contains(task.Depends, id) })
+	return file.ValidateDependencies(storage, task.ID, added)
+}
+
+// Whether the fields tasks edit changes are the same in both tasks
+func sameEditable(a, b file.Task) bool {
+	return a.Task == b.Task && a.Priority == b.Priority && a.Project == b.Project &&
+		slices.Equal(a.Tags, b.Tags) && a.Due.Equal(b.Due) &&
+		a.Parent == b.Parent && slices.Equal(a.Depends, b.Depends)
+}
+
+// Open path in $VISUAL or $EDITOR, vi if neither is set
+func runEditor(cmd *cobra.Command, path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// The editor may come with arguments, like "code --wait"
+	fields := strings.Fields(editor)
+	editorCmd := exec.Command(fields[0], append(fields[1:], path)...) // #nosec G204 -- the user picks their own editor
+	editorCmd.Stdin = cmd.InOrStdin()
+	editorCmd.Stdout = cmd.OutOrStdout()
+	editorCmd.Stderr = cmd.ErrOrStderr()
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("error running editor %q: %w", editor, err)
+	}
+	return nil
+}
+
+// editCmd represents the edit command
+func newEditCmd(storage *file.Repository) *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit",
+		Short: "edit a task in your editor",
+		Long: `edit a task in $EDITOR as YAML
+tasks edit <task ID>
+
+The task is checked when the editor is closed, if it is not valid you can
+edit it again or cancel. The status is changed with tasks start, complete,
+wait, block, cancel and reopen instead.
+
+Changes made to the task while the editor is open, like a note added with
+tasks annotate, are kept. The edit is not saved when they touch the fields
+being edited.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("please provide exactly one task ID to edit")
+			}
+
+			taskID, err := parseTaskID(*storage, args[0])
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+
+			task, err := (*storage).Get(taskID)
+			if err != nil {
+				return fmt.Errorf("error editing task: %w", err)
+			}
+			if task.Deleted() {
+				return deletedError(task)
+			}
+			data, err := renderEditable(task)
+			if err != nil {
+				return err
+			}
+
+			tmp, err := os.CreateTemp("", fmt.Sprintf("task-%d-*.yaml", task.ID))
+			if err != nil {
+				return fmt.Errorf("error creating temporary file: %w", err)
+			}
+			defer os.Remove(tmp.Name())
+			if _, err := tmp.Write(data); err != nil {
+				_ = tmp.Close()
+				return fmt.Errorf("error writing temporary file: %w", err)
+			}
+			if err := tmp.Close(); err != nil {
+				return fmt.Errorf("error writing temporary file: %w", err)
+			}
+
+			for {
+				if err := runEditor(cmd, tmp.Name()); err != nil {
+					return err
+				}
+				edited, err := os.ReadFile(tmp.Name())
+				if err != nil {
+					return fmt.Errorf("error reading temporary file: %w", err)
+				}
+
+				updated, ok, err := applyEditable(task, edited)
+				if err == nil && ok {
+					err = validateEdited(*storage, task, updated)
+				}
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Invalid task: %v\n", err)
+					if confirm(cmd.InOrStdin(), cmd.OutOrStdout(), "Edit it again") {
+						continue
+					}
+					return fmt.Errorf("task %d was not changed", task.ID)
+				}
+				if !ok {
+					fmt.Fprintln(cmd.OutOrStdout(), "Edit cancelled")
+					return nil
+				}
+
+				// The task is read again, anything done to it while the
+				// editor was open is kept. Unless it changed what was edited
+				updated, err = file.UpdateTask(*storage, task.ID, func(current *file.Task) error {
+					if current.Deleted() || !sameEditable(*current, task) {
+						return fmt.Errorf("task %d was changed while it was being edited, edit it again", task.ID)
+					}
+					current.Task, current.Priority, current.Project = updated.Task, updated.Priority, updated.Project
+					current.Tags, current.Due = updated.Tags, updated.Due
+					current.Parent, current.Depends = updated.Parent, updated.Depends
+					return nil
+				})
+				if err != nil {
+					return fmt.Errorf("error editing task: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Modified task %d: %s\n", updated.ID, updated.Task)
+				return nil
+			}
+		},
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+func newExportCmd(storage *file.Repository) *cobra.Command {
+	var format, output string
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "export all tasks to a file",
+		Long: `export all tasks, including completed ones, to a file or stdout
+tasks export --format csv > tasks.csv
+tasks export --output tasks.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("this command does not accept any arguments")
+			}
+			if format == "" {
+				format = file.FormatFromPath(output)
+			}
+			if format == "" {
+				format = "json"
+			}
+			cmd.SilenceUsage = true
+
+			snap, err := (*storage).Export()
+			if err != nil {
+				return fmt.Errorf("error reading tasks: %w", err)
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "" && output != "-" {
+				f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+				if err != nil {
+					return fmt.Errorf("error creating %s: %w", output, err)
+				}
+				defer f.Close()
+				w = f
+			}
+
+			if err := file.Encode(w, format, snap); err != nil {
+				return fmt.Errorf("error exporting tasks: %w", err)
+			}
+			if output != "" && output != "-" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Exported %d tasks to %s\n", len(snap.Tasks), output)
+			}
+			return nil
+		},
+	}
+
+	exportCmd.Flags().StringVar(&format, "format", "", "output format ("+strings.Join(file.Formats, ", ")+"), guessed from --output by default")
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "file to write to instead of stdout")
+	return exportCmd
+}
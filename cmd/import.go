@@ -0,0 +1,94 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/spf13/cobra"
+)
+
+// importCmd represents the import command
+func newImportCmd(storage *file.Repository) *cobra.Command {
+	var (
+		format  string
+		replace bool
+	)
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "import tasks from a file",
+		Long: `import tasks from a file written by tasks export (or a CSV/JSON tasks file)
+tasks import <file> to add the tasks in the file to the current ones
+tasks import --replace <file> to replace the current tasks
+tasks import - to read from stdin
+
+Imported tasks get new IDs unless --replace is used, and tasks that already
+exist (same description and creation time) are skipped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("please provide exactly one file to import")
+			}
+			path := args[0]
+			if format == "" {
+				format = file.FormatFromPath(path)
+			}
+			if format == "" {
+				return fmt.Errorf("cannot guess the format of %s, use --format", path)
+			}
+			cmd.SilenceUsage = true
+
+			var r io.Reader = cmd.InOrStdin()
+			if path != "-" {
+				f, err := os.Open(path)
+				if err != nil {
+					return fmt.Errorf("error opening %s: %w", path, err)
+				}
+				defer f.Close()
+				r = f
+			}
+
+			incoming, err := file.Decode(r, format)
+			if err != nil {
+				return fmt.Errorf("error reading %s: %w", path, err)
+			}
+
+			current, err := (*storage).Export()
+			if err != nil {
+				return fmt.Errorf("error reading tasks: %w", err)
+			}
+
+			result := file.Import(current, incoming, replace)
+			if err := (*storage).Replace(result.Snapshot); err != nil {
+				return fmt.Errorf("error importing tasks: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Imported %d tasks\n", result.Imported)
+			oldIDs := make([]int, 0, len(result.Remapped))
+			for oldID := range result.Remapped {
+				oldIDs = append(oldIDs, oldID)
+			}
+			slices.Sort(oldIDs)
+			for _, oldID := range oldIDs {
+				fmt.Fprintf(out, "  task %d imported as %d\n", oldID, result.Remapped[oldID])
+			}
+			if len(result.Duplicates) > 0 {
+				fmt.Fprintf(out, "Skipped %d duplicate tasks:\n", len(result.Duplicates))
+				for _, task := range result.Duplicates {
+					fmt.Fprintf(out, "  %d %s\n", task.ID, task.Task)
+				}
+			}
+			return nil
+		},
+	}
+
+	importCmd.Flags().StringVar(&format, "format", "", "input format ("+strings.Join(file.Formats, ", ")+"), guessed from the file extension by default")
+	importCmd.Flags().BoolVar(&replace, "replace", false, "replace the current tasks instead of merging")
+	return importCmd
+}
@@ -0,0 +1,233 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/spf13/cobra"
+)
+
+// Fields of a task that differ between two versions of it, like "status,
+// completed at". The modification time always does
+func changedFields(before, after file.Task) []string {
+	var fields []string
+	b, a := reflect.ValueOf(before), reflect.ValueOf(after)
+	for i := range b.NumField() {
+		name := b.Type().Field(i).Name
+		if name == "ModifiedAt" || reflect.DeepEqual(b.Field(i).Interface(), a.Field(i).Interface()) {
+			continue
+		}
+		// CompletedAt becomes completed at
+		var words strings.Builder
+		for j, r := range name {
+			if j > 0 && unicode.IsUpper(r) {
+				words.WriteByte(' ')
+			}
+			words.WriteRune(unicode.ToLower(r))
+		}
+		fields = append(fields, strings.ReplaceAll(words.String(), "task", "description"))
+	}
+	return fields
+}
+
+// Whether change is to the task with id and uuid. Tasks are told apart by
+// their UUID, entries written before UUIDs existed only have the ID
+func changeOf(change file.JournalEntry, id int, uuid string) bool {
+	for _, task := range []*file.Task{change.Before, change.After} {
+		if task != nil && task.UUID != "" && uuid != "" {
+			return task.UUID == uuid
+		}
+	}
+	return change.ID == id
+}
+
+// One line about a change, like "deleted task 3: Buy milk"
+func describeChange(change file.JournalEntry) string {
+	switch {
+	case change.Before == nil && change.After == nil:
+		return fmt.Sprintf("nothing changed on task %d", change.ID)
+	case change.Before == nil:
+		return fmt.Sprintf("created task %d: %s", change.ID, change.After.Task)
+	case change.After == nil && change.Archive != "":
+		return fmt.Sprintf("archived task %d: %s", change.ID, change.Before.Task)
+	case change.After == nil && change.Before.Deleted():
+		return fmt.Sprintf("purged task %d: %s", change.ID, change.Before.Task)
+	case change.After == nil, !change.Before.Deleted() && change.After.Deleted():
+		return fmt.Sprintf("deleted task %d: %s", change.ID, change.Before.Task)
+	case change.Before.Deleted() && !change.After.Deleted():
+		return fmt.Sprintf("restored task %d: %s", change.ID, change.After.Task)
+	}
+	fields := changedFields(*change.Before, *change.After)
+	if len(fields) == 0 {
+		return fmt.Sprintf("stored task %d: %s", change.ID, change.After.Task)
+	}
+	return fmt.Sprintf("changed task %d: %s (%s)", change.ID, change.After.Task, strings.Join(fields, ", "))
+}
+
+// The changes of op, the most recent last
+func writeOperation(w io.Writer, op file.Operation) {
+	title := op.Command
+	switch {
+	case op.Undoes != 0:
+		title = fmt.Sprintf("undo of %d", op.Undoes)
+	case op.Redoes != 0:
+		title = fmt.Sprintf("redo of %d", op.Redoes)
+	case op.Undone:
+		title += " (undone)"
+	}
+	fmt.Fprintf(w, "%d  %s  %s\n", op.Op, op.At.Local().Format(completedLayout), title)
+	for _, change := range op.Changes {
+		fmt.Fprintf(w, "    %s\n", cellReplacer.Replace(describeChange(change)))
+	}
+}
+
+// How many operations tasks undo and redo go through, one by default
+func operationCount(args []string) (int, error) {
+	if len(args) == 0 {
+		return 1, nil
+	}
+	if len(args) > 1 {
+		return 0, fmt.Errorf("please provide at most one number of changes")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number of changes: %s", args[0])
+	}
+	return n, nil
+}
+
+// Run tasks undo or redo
+func runReplay(cmd *cobra.Command, args []string, force bool, replay func(n int, force bool) ([]file.Operation, error), done string) error {
+	n, err := operationCount(args)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	ops, err := replay(n, force)
+	if errors.Is(err, file.ErrJournalConflict) || errors.Is(err, file.ErrNotFound) {
+		return fmt.Errorf("%w, --force %s it anyway", err, cmd.Name())
+	}
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %d: %s\n", done, op.Op, op.Command)
+	}
+	return nil
+}
+
+const replayHelp = `
+Changes are the commands that changed tasks, like tasks complete 3 or tasks
+delete +old, see tasks log. A change is not undone or redone when one of its
+tasks was changed again after it, unless --force is given.`
+
+// undoCmd represents the undo command
+func newUndoCmd(storage *file.Repository, journal **file.Journal) *cobra.Command {
+	var force bool
+	undoCmd := &cobra.Command{
+		Use:   "undo",
+		Short: "revert the last changes to the tasks",
+		Long: `revert the last changes to the tasks, the most recent first
+tasks undo to revert the last change
+tasks undo <N> to revert the last N changes
+` + replayHelp,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReplay(cmd, args, force, func(n int, force bool) ([]file.Operation, error) {
+				return (*journal).Undo(*storage, n, force)
+			}, "Undid")
+		},
+	}
+	undoCmd.Flags().BoolVarP(&force, "force", "f", false, "undo even if the tasks changed since")
+	return undoCmd
+}
+
+// redoCmd represents the redo command
+func newRedoCmd(storage *file.Repository, journal **file.Journal) *cobra.Command {
+	var force bool
+	redoCmd := &cobra.Command{
+		Use:   "redo",
+		Short: "make the last undone changes again",
+		Long: `make the changes reverted by tasks undo again, the last undone first
+tasks redo to make the last undone change again
+tasks redo <N> to make the last N undone changes again
+
+Changes can be redone until tasks are changed by any other command.
+` + replayHelp,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReplay(cmd, args, force, func(n int, force bool) ([]file.Operation, error) {
+				return (*journal).Redo(*storage, n, force)
+			}, "Redid")
+		},
+	}
+	redoCmd.Flags().BoolVarP(&force, "force", "f", false, "redo even if the tasks changed since")
+	return redoCmd
+}
+
+// logCmd represents the log command
+func newLogCmd(storage *file.Repository, journal **file.Journal) *cobra.Command {
+	var limit int
+	logCmd := &cobra.Command{
+		Use:   "log",
+		Short: "show the history of changes to the tasks",
+		Long: `show the changes made to the tasks, the most recent first
+tasks log to see the last changes, along with their number and when they were made
+tasks log <task ID> to only see the changes to a task, also by the start of its UUID
+
+Changes undone with tasks undo are marked as such. The history is kept in a
+file next to the tasks, with a .journal extension.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var id int
+			var uuid string
+			if len(args) > 1 {
+				return fmt.Errorf("please provide at most one task ID")
+			}
+			if len(args) == 1 {
+				var err error
+				if id, err = parseTaskID(*storage, args[0]); err != nil {
+					return err
+				}
+				// Purged tasks are only known by their ID
+				if task, err := (*storage).Get(id); err == nil {
+					uuid = task.UUID
+				}
+			}
+			cmd.SilenceUsage = true
+
+			ops, err := (*journal).Operations()
+			if err != nil {
+				return fmt.Errorf("error reading history: %w", err)
+			}
+			if id != 0 {
+				ops = slices.DeleteFunc(ops, func(op file.Operation) bool {
+					return !slices.ContainsFunc(op.Changes, func(change file.JournalEntry) bool { return changeOf(change, id, uuid) })
+				})
+			}
+			if len(ops) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No changes recorded")
+				return nil
+			}
+
+			slices.Reverse(ops)
+			if limit > 0 && len(ops) > limit {
+				ops = ops[:limit]
+			}
+			for _, op := range ops {
+				writeOperation(cmd.OutOrStdout(), op)
+			}
+			return nil
+		},
+	}
+	logCmd.Flags().IntVarP(&limit, "limit", "n", 10, "show at most this many changes, 0 for all")
+	return logCmd
+}
@@ -6,31 +6,125 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 // listCmd represents the list command
-func newListCmd(storage *file.FileStorage) *cobra.Command {
+func newListCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	var sortBy, format, columns, tmpl string
+	var archived bool
 	var listCmd = &cobra.Command{
 		Use:   "list",
 		Short: "list all tasks",
+		Long: `list open tasks that are not waiting, the most urgent first
+tasks list --all to include completed, cancelled and waiting tasks, along with
+  when each task was last modified and completed
+tasks list --sort status to group tasks by status
+tasks list --sort due,-priority to sort by due date and then by lowest priority
+tasks list <filter> to list the tasks matching a filter, like
+  tasks list +bug project:work
+  tasks list "due.before:fri and (priority:H or +urgent)"
+  tasks list status:completed not +bug
+  tasks list status:started
+  tasks list 1,3,5-9
+tasks list --archived to list the tasks moved away by tasks archive instead
+
+Filters combine IDs, +tag, -tag, project:, status:, priority:, due:,
+due.before:, due.after:, created.before:, created.after: and words of the
+description with and, or, not and parentheses.
+
+status: takes pending, started, waiting, blocked, done, cancelled, open (any of
+the first four), closed (done or cancelled), recurring (templates) or all.
+
+Subtasks are listed under their parent task, indented. Tasks that depend on
+pending tasks show which ones block them.
+
+Urgency grows with the priority, the closeness of the due date and the age of
+a task. Started tasks are more urgent, waiting and blocked ones less.
+
+tasks list --format json to write tasks as json, other formats are ndjson (one
+  json object per line), csv, yaml, markdown and table (the default)
+tasks list --columns id,desc,due,tags to choose the columns and their order,
+  see below for the column names. json, ndjson, csv and yaml write every
+  column by default
+tasks list --template '{{.ID}}: {{.Task}} {{join .Tags ","}}' to write each
+  task with a Go template (https://pkg.go.dev/text/template), it is given the
+  task as stored. Besides the builtin functions, join joins a list of strings,
+  ids a list of task IDs and date formats a time with a Go layout, like
+  {{date "2006-01-02" .Due}}
+
+Columns: ` + strings.Join(columnNames(), ", "),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) > 0 {
-				return fmt.Errorf("this command does not accept any arguments")
+			var out output
+			var err error
+			switch {
+			case tmpl != "" && (cmd.Flags().Changed("format") || columns != ""):
+				return fmt.Errorf("--template cannot be combined with --format or --columns")
+			case tmpl != "":
+				if out.template, err = parseTemplate(tmpl); err != nil {
+					return err
+				}
+			case !slices.Contains(outputFormats, format):
+				return fmt.Errorf("invalid format %q, use one of %s", format, strings.Join(outputFormats, ", "))
+			}
+			out.format = format
+			if columns != "" {
+				if out.columns, err = parseColumns(columns); err != nil {
+					return err
+				}
+			}
+
+			all := viper.GetBool("all")
+			var tasks []file.Task
+			if archived {
+				all = true
+				tasks, err = selectArchived(cfg, args)
+			} else {
+				tasks, err = selectTasks(*storage, args, all)
+			}
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
 			}
 
-			if err := (*storage).ListTasks(cmd.OutOrStdout()); err != nil {
-				fmt.Fprintf(os.Stderr, "Error listing tasks: %v\n", err)
+			if len(tasks) == 0 && out.forPeople() {
+				fmt.Fprintln(cmd.OutOrStdout(), "No tasks found")
+				return nil
+			}
+
+			if err := sortTasks(tasks, sortBy, time.Now()); err != nil {
+				return err
+			}
+
+			blocking, err := blockingTasks(*storage)
+			if err != nil {
 				return fmt.Errorf("error listing tasks: %w", err)
 			}
-			return nil
+
+			cmd.SilenceUsage = true
+			ctx := printContext{blocking: blocking, timestamps: all}
+			if !out.forPeople() {
+				return writeTasks(cmd.OutOrStdout(), tasks, ctx, out)
+			}
+			if out.format == "table" {
+				fmt.Fprintln(cmd.OutOrStdout(), "Total tasks:", len(tasks))
+			}
+			return writeTasks(cmd.OutOrStdout(), treeOrder(tasks), ctx, out)
 		},
 	}
 
-	listCmd.Flags().BoolP("all", "a", false, "List all tasks including completed ones")
+	listCmd.Flags().BoolP("all", "a", false, "List all tasks including closed and waiting ones")
+	listCmd.Flags().BoolVar(&archived, "archived", false, "List the archived tasks instead")
+	listCmd.Flags().StringVarP(&sortBy, "sort", "s", "urgency", "comma separated sort keys: urgency, id, created, due, priority, description, status (prefix with - to reverse)")
+	listCmd.Flags().StringVar(&format, "format", "table", "output format: "+strings.Join(outputFormats, ", "))
+	listCmd.Flags().StringVar(&columns, "columns", "", "comma separated columns to show, like id,desc,due,tags")
+	listCmd.Flags().StringVar(&tmpl, "template", "", "Go template to write each task with")
 	if err := viper.BindPFlag("all", listCmd.Flags().Lookup("all")); err != nil {
 		fmt.Fprintf(os.Stderr, "error binding --all flag: %v\n", err)
 	}
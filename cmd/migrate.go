@@ -0,0 +1,74 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+func newMigrateCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	var to, path string
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "convert the tasks to another storage type",
+		Long: `convert the tasks to another storage type and make it the configured one
+tasks migrate --to json
+
+The previous tasks file is left untouched.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("this command does not accept any arguments")
+			}
+			if path == "" {
+				path = cfg.Filepath
+			}
+			if to == cfg.Storage && path == cfg.Filepath {
+				return fmt.Errorf("tasks are already stored as %s", to)
+			}
+			cmd.SilenceUsage = true
+
+			target, err := file.SelectStorage(path, to)
+			if err != nil {
+				return err
+			}
+
+			existing, err := target.Export()
+			if err != nil {
+				return fmt.Errorf("error reading %s storage: %w", to, err)
+			}
+			if len(existing.Tasks) > 0 {
+				return fmt.Errorf("the %s storage already has %d tasks, use tasks import to merge them", to, len(existing.Tasks))
+			}
+
+			snap, err := (*storage).Export()
+			if err != nil {
+				return fmt.Errorf("error reading tasks: %w", err)
+			}
+			if err := target.Replace(snap); err != nil {
+				return fmt.Errorf("error writing tasks: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Migrated %d tasks from %s to %s\n", len(snap.Tasks), cfg.Storage, to)
+
+			cfg.Storage = to
+			cfg.Filepath = path
+			if err := config.Save(*cfg); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Could not update the config file, set \"storage: %s\" and \"filepath: %s\" manually: %v\n", to, path, err)
+			}
+			return nil
+		},
+	}
+
+	migrateCmd.Flags().StringVar(&to, "to", "", "storage type to convert to (csv, json or sqlite)")
+	migrateCmd.Flags().StringVar(&path, "filepath", "", "path of the new tasks file without extension, the current one by default")
+	if err := migrateCmd.MarkFlagRequired("to"); err != nil {
+		fmt.Fprintf(os.Stderr, "error marking --to flag as required: %v\n", err)
+	}
+	return migrateCmd
+}
@@ -0,0 +1,227 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
+	"github.com/MoXcz/tasks/internal/dates"
+	"github.com/spf13/cobra"
+)
+
+// Changes to apply to a task, nil fields are left as they are
+type modification struct {
+	description string
+	addTags     []string
+	removeTags  []string
+	project     *string
+	priority    *file.Priority
+	// Zero removes the due date
+	due *time.Time
+	// Zero makes it a top level task
+	parent        *int
+	addDepends    []int
+	removeDepends []int
+	clearDepends  bool
+}
+
+func (m modification) empty() bool {
+	return m.description == "" && len(m.addTags) == 0 && len(m.removeTags) == 0 &&
+		m.project == nil && m.priority == nil && m.due == nil && m.parent == nil &&
+		len(m.addDepends) == 0 && len(m.removeDepends) == 0 && !m.clearDepends
+}
+
+func (m modification) apply(task file.Task) (file.Task, error) {
+	if m.description != "" {
+		task.Task = m.description
+	}
+
+	tags := slices.Clone(task.Tags)
+	tags = append(tags, m.addTags...)
+	tags = slices.DeleteFunc(tags, func(tag string) bool { return slices.Contains(m.removeTags, tag) })
+	task.Tags = file.NormalizeTags(tags)
+	for _, tag := range task.Tags {
+		if err := file.ValidateTag(tag); err != nil {
+			return file.Task{}, err
+		}
+	}
+
+	if m.project != nil {
+		if err := file.ValidateProject(*m.project); err != nil {
+			return file.Task{}, err
+		}
+		task.Project = *m.project
+	}
+	if m.priority != nil {
+		task.Priority = *m.priority
+	}
+	if m.due != nil {
+		task.Due = *m.due
+	}
+	if m.parent != nil {
+		task.Parent = *m.parent
+	}
+	if m.clearDepends {
+		task.Depends = nil
+	}
+	depends := append(slices.Clone(task.Depends), m.addDepends...)
+	depends = slices.DeleteFunc(depends, func(id int) bool { return slices.Contains(m.removeDepends, id) })
+	task.Depends = file.NormalizeIDs(depends)
+	return task, nil
+}
+
+// Build a modification from the words, +tag, -tag, project:name and depends:id given as
+// arguments and from the flags of cmd
+func parseModification(cmd *cobra.Command, storage file.Repository, args []string) (modification, error) {
+	attrs, err := parseAttributes(storage, args)
+	if err != nil {
+		return modification{}, err
+	}
+	m := modification{
+		description: strings.Join(attrs.words, " "),
+		addTags:     attrs.addTags,
+		removeTags:  attrs.removeTags,
+
+		addDepends:    attrs.addDepends,
+		removeDepends: attrs.removeDepends,
+		clearDepends:  attrs.clearDepends,
+	}
+	if attrs.hasProject {
+		m.project = &attrs.project
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("project") {
+		project, _ := flags.GetString("project")
+		m.project = &project
+	}
+	if flags.Changed("tag") {
+		tags, _ := flags.GetStringSlice("tag")
+		m.addTags = append(m.addTags, tags...)
+	}
+	if flags.Changed("remove-tag") {
+		tags, _ := flags.GetStringSlice("remove-tag")
+		m.removeTags = append(m.removeTags, tags...)
+	}
+	if flags.Changed("priority") {
+		value, _ := flags.GetString("priority")
+		priority, err := file.ParsePriority(value)
+		if err != nil {
+			return modification{}, err
+		}
+		m.priority = &priority
+	}
+	if flags.Changed("due") {
+		value, _ := flags.GetString("due")
+		var due time.Time
+		if value != "none" && value != "" {
+			dueAt, err := dates.Parse(value, time.Now())
+			if err != nil {
+				return modification{}, fmt.Errorf("invalid due date: %w", err)
+			}
+			due = dueAt.UTC()
+		}
+		m.due = &due
+	}
+	if flags.Changed("parent") {
+		value, _ := flags.GetString("parent")
+		var parent int
+		if value != "none" && value != "" {
+			if parent, err = parseTaskID(storage, value); err != nil {
+				return modification{}, fmt.Errorf("invalid parent: %w", err)
+			}
+		}
+		m.parent = &parent
+	}
+	return m, nil
+}
+
+// modifyCmd represents the modify command
+func newModifyCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	modifyCmd := &cobra.Command{
+		Use:   "modify",
+		Short: "modify a task",
+		Long: `modify a task, keeping its ID
+tasks modify <task ID> <new description> to change its description
+tasks modify <task ID> +tag project:name to add a tag and move it to a project
+tasks modify <task ID> -- -tag to remove a tag (-- keeps it from being read as a flag)
+tasks modify <task ID> project: to remove it from its project
+tasks modify <task ID> --due <date> to change its due date, none to remove it
+tasks modify <task ID> --priority <H|M|L|none> to change its priority
+tasks modify <task ID> depends:3,4 to only start it once tasks 3 and 4 are completed
+tasks modify <task ID> depends:-3 to drop a dependency, depends: to drop them all
+tasks modify <task ID> --parent <task ID|none> to make it a subtask of another task or a top level one
+
+The task ID can also be a filter, quoted when it has more than one word, to
+change every pending task matching it:
+  tasks modify 1,3,5-9 +errand
+  tasks modify "+bug and project:work" --priority H
+
+Modifying more tasks than confirm_threshold (3 by default) at once asks for
+confirmation first, --force skips it. See tasks list --help for filters.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("please provide the ID of the task to modify")
+			}
+
+			m, err := parseModification(cmd, *storage, args[1:])
+			if err != nil {
+				return err
+			}
+			if m.empty() {
+				return fmt.Errorf("nothing to modify, see tasks modify --help")
+			}
+
+			tasks, err := selectTasks(*storage, args[:1], false)
+			if err != nil {
+				return fmt.Errorf("error modifying task: %w", err)
+			}
+			cmd.SilenceUsage = true
+			if len(tasks) == 0 {
+				return fmt.Errorf("no tasks match %q", args[0])
+			}
+
+			force, _ := cmd.Flags().GetBool("force")
+			if !force && len(tasks) > confirmThreshold(cfg) && !confirmBulk(cmd, "modify", tasks) {
+				return nil
+			}
+
+			for _, task := range tasks {
+				if m.parent != nil {
+					if err := file.ValidateParent(*storage, task.ID, *m.parent); err != nil {
+						return fmt.Errorf("error modifying task %d: %w", task.ID, err)
+					}
+				}
+				task, err = m.apply(task)
+				if err != nil {
+					return fmt.Errorf("error modifying task: %w", err)
+				}
+				if len(m.addDepends) > 0 {
+					if err := file.ValidateDependencies(*storage, task.ID, m.addDepends); err != nil {
+						return fmt.Errorf("error modifying task %d: %w", task.ID, err)
+					}
+				}
+				if _, err := (*storage).Update(task); err != nil {
+					return fmt.Errorf("error modifying task: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Modified task %d: %s\n", task.ID, task.Task)
+			}
+			return nil
+		},
+	}
+
+	modifyCmd.Flags().String("due", "", "new due date of the task, none to remove it")
+	modifyCmd.Flags().StringP("priority", "p", "", "new priority of the task (H, M, L or none)")
+	modifyCmd.Flags().StringSliceP("tag", "t", nil, "tags to add, can be repeated")
+	modifyCmd.Flags().StringSliceP("remove-tag", "T", nil, "tags to remove, can be repeated")
+	modifyCmd.Flags().String("project", "", "new project of the task, empty to remove it")
+	modifyCmd.Flags().String("parent", "", "ID of the new parent task, none to make it a top level task")
+	modifyCmd.Flags().BoolP("force", "f", false, "Skip confirmation")
+	return modifyCmd
+}
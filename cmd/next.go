@@ -0,0 +1,59 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/spf13/cobra"
+)
+
+// nextCmd represents the next command
+func newNextCmd(storage *file.Repository) *cobra.Command {
+	var limit int
+	nextCmd := &cobra.Command{
+		Use:   "next",
+		Short: "list the tasks that can be started right away",
+		Long: `list the most urgent tasks that can be started right away, leaving out the
+blocked tasks, those that depend on pending tasks and those with pending subtasks
+tasks next <filter> to only look at the tasks matching a filter
+tasks next --limit 0 to list every one of them`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tasks, err := selectTasks(*storage, args, false)
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
+			}
+			cmd.SilenceUsage = true
+
+			pending, err := (*storage).List(file.Filter{})
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
+			}
+			actionable := tasks[:0]
+			for _, task := range tasks {
+				if task.Closed() || task.IsTemplate() || task.StatusAt(time.Now()) == file.StatusBlocked || len(file.BlockingTasks(pending, task)) > 0 || len(file.Descendants(pending, task.ID)) > 0 {
+					continue
+				}
+				actionable = append(actionable, task)
+			}
+
+			if len(actionable) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No tasks found")
+				return nil
+			}
+			if err := sortTasks(actionable, "urgency", time.Now()); err != nil {
+				return err
+			}
+			if limit > 0 && len(actionable) > limit {
+				actionable = actionable[:limit]
+			}
+			return printTasks(cmd.OutOrStdout(), actionable, printContext{})
+		},
+	}
+
+	nextCmd.Flags().IntVarP(&limit, "limit", "n", 10, "maximum number of tasks to list, 0 for no limit")
+	return nextCmd
+}
@@ -0,0 +1,247 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats tasks list can write
+var outputFormats = []string{"table", "json", "ndjson", "csv", "yaml", "markdown"}
+
+// How tasks are written, the zero value is the default table
+type output struct {
+	format string
+	// Nil for the default ones: those of the table for table and markdown,
+	// every column for the other formats
+	columns []column
+	// Rendered once per task instead of using format
+	template *template.Template
+}
+
+// Whether the format is meant to be read by people, who get tree indentation
+// and a count of the tasks
+func (o output) forPeople() bool {
+	return o.template == nil && (o.format == "" || o.format == "table" || o.format == "markdown")
+}
+
+// Functions available to --template besides the builtin ones
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"ids":  joinIDs,
+	// Empty for zero times, like a missing due date
+	"date": func(layout string, t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Local().Format(layout)
+	},
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("task").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func writeTasks(w io.Writer, tasks []file.Task, ctx printContext, out output) error {
+	ctx.now = time.Now()
+	if out.template != nil {
+		return writeTemplate(w, tasks, out.template)
+	}
+
+	columns := out.columns
+	switch out.format {
+	case "", "table":
+		return writeTable(w, tasks, ctx, columns)
+	case "markdown":
+		return writeMarkdown(w, tasks, ctx, columns)
+	}
+
+	if columns == nil {
+		columns = tableColumns
+	}
+	records := make([]record, len(tasks))
+	for i, task := range tasks {
+		records[i] = newRecord(task, ctx, columns)
+	}
+	switch out.format {
+	case "json":
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal tasks: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return fmt.Errorf("could not write task: %w", err)
+			}
+		}
+		return nil
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(records); err != nil {
+			return fmt.Errorf("could not write tasks: %w", err)
+		}
+		return enc.Close()
+	case "csv":
+		return writeRecordsCSV(w, records, columns)
+	}
+	return fmt.Errorf("invalid format %q, use one of %s", out.format, strings.Join(outputFormats, ", "))
+}
+
+// Every task is rendered on its own line, unless the template already ends
+// with a newline
+func writeTemplate(w io.Writer, tasks []file.Task, tmpl *template.Template) error {
+	var buf bytes.Buffer
+	for _, task := range tasks {
+		buf.Reset()
+		if err := tmpl.Execute(&buf, task); err != nil {
+			return fmt.Errorf("error rendering task %d: %w", task.ID, err)
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var markdownReplacer = strings.NewReplacer("|", "\\|")
+
+func writeMarkdown(w io.Writer, tasks []file.Task, ctx printContext, columns []column) error {
+	headers, rows := tableRows(tasks, ctx, columns)
+	separators := make([]string, len(headers))
+	for i := range separators {
+		separators[i] = "---"
+	}
+
+	var buf bytes.Buffer
+	for _, cells := range append([][]string{headers, separators}, rows...) {
+		for i := range cells {
+			cells[i] = markdownReplacer.Replace(cells[i])
+		}
+		fmt.Fprintf(&buf, "| %s |\n", strings.Join(cells, " | "))
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// The values of the columns of a task, in the order of the columns
+type record []recordField
+
+type recordField struct {
+	name  string
+	value any
+}
+
+func newRecord(task file.Task, ctx printContext, columns []column) record {
+	r := make(record, len(columns))
+	for i, col := range columns {
+		r[i] = recordField{name: col.name, value: col.data(task, ctx)}
+	}
+	return r
+}
+
+// An object with the keys in the order of the columns
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// A mapping with the keys in the order of the columns
+func (r record) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range r {
+		var value yaml.Node
+		if err := value.Encode(field.value); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.name}, &value)
+	}
+	return node, nil
+}
+
+// One row per task with a header of column names. Lists are space separated
+// and times are in RFC 3339 and UTC, which spreadsheets read unlike the RFC
+// 1123 times of the CSV storage
+func writeRecordsCSV(w io.Writer, records []record, columns []column) error {
+	csvW := csv.NewWriter(w)
+	if err := csvW.Write(columnNamesOf(columns)); err != nil {
+		return fmt.Errorf("could not write header: %w", err)
+	}
+	for _, r := range records {
+		row := make([]string, len(r))
+		for i, field := range r {
+			row[i] = formatCSVValue(field.value)
+		}
+		if err := csvW.Write(row); err != nil {
+			return fmt.Errorf("could not write task: %w", err)
+		}
+	}
+	csvW.Flush()
+	return csvW.Error()
+}
+
+func columnNamesOf(columns []column) []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.name
+	}
+	return names
+}
+
+func formatCSVValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case []string:
+		return strings.Join(v, " ")
+	case []int:
+		return joinIDs(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,314 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/mergestat/timediff"
+)
+
+// What columns know about besides the task they show
+type printContext struct {
+	// Set by printTasks and writeTasks
+	now time.Time
+	// Pending dependencies of blocked tasks, see file.BlockingTasks. Nil when
+	// that is not known
+	blocking map[int][]int
+	// Show when tasks were last modified and completed in the default table
+	timestamps bool
+}
+
+type column struct {
+	// Given to --columns, also the key of the value in structured formats
+	name   string
+	header string
+	// Shown in tables
+	value func(task file.Task, ctx printContext) string
+	// Written by the structured formats, nil when there is no value
+	data func(task file.Task, ctx printContext) any
+	// Hidden from the default table when no task has a value for it
+	optional bool
+	// Only in the default table when ctx.timestamps is set
+	timestamp bool
+	// Never in the default table, only when asked for with --columns
+	extra bool
+}
+
+// Every column, the default table shows them in this order
+var tableColumns = []column{
+	{
+		name: "id", header: "ID",
+		value: func(t file.Task, _ printContext) string { return strconv.Itoa(t.ID) },
+		data:  func(t file.Task, _ printContext) any { return t.ID },
+	},
+	{
+		name: "uuid", header: "UUID", extra: true,
+		value: func(t file.Task, _ printContext) string { return t.UUID },
+		data:  func(t file.Task, _ printContext) any { return t.UUID },
+	},
+	{
+		name: "description", header: "Task",
+		value: func(t file.Task, _ printContext) string { return t.Task },
+		data:  func(t file.Task, _ printContext) any { return t.Task },
+	},
+	{
+		name: "priority", header: "Pri",
+		value: func(t file.Task, _ printContext) string { return string(t.Priority) },
+		data:  func(t file.Task, _ printContext) any { return string(t.Priority) },
+	},
+	{
+		name: "project", header: "Project", optional: true,
+		value: func(t file.Task, _ printContext) string { return t.Project },
+		data:  func(t file.Task, _ printContext) any { return t.Project },
+	},
+	{
+		name: "tags", header: "Tags", optional: true,
+		value: func(t file.Task, _ printContext) string { return strings.Join(t.Tags, " ") },
+		data:  func(t file.Task, _ printContext) any { return append([]string{}, t.Tags...) },
+	},
+	{
+		name: "blocked", header: "Blocked by", optional: true,
+		value: func(t file.Task, ctx printContext) string { return joinIDs(ctx.blocking[t.ID]) },
+		data:  func(t file.Task, ctx printContext) any { return append([]int{}, ctx.blocking[t.ID]...) },
+	},
+	{
+		name: "depends", header: "Depends", extra: true,
+		value: func(t file.Task, _ printContext) string { return joinIDs(t.Depends) },
+		data:  func(t file.Task, _ printContext) any { return append([]int{}, t.Depends...) },
+	},
+	{
+		name: "parent", header: "Parent", extra: true,
+		value: func(t file.Task, _ printContext) string { return formatOptionalID(t.Parent) },
+		data:  func(t file.Task, _ printContext) any { return optionalData(t.Parent) },
+	},
+	{
+		name: "created", header: "Created",
+		value: func(t file.Task, ctx printContext) string {
+			return timediff.TimeDiff(t.CreatedAt, timediff.WithStartTime(ctx.now))
+		},
+		data: func(t file.Task, _ printContext) any { return optionalData(t.CreatedAt) },
+	},
+	{
+		name: "modified", header: "Modified", optional: true, timestamp: true,
+		value: func(t file.Task, ctx printContext) string {
+			if t.ModifiedAt.IsZero() {
+				return ""
+			}
+			return timediff.TimeDiff(t.ModifiedAt, timediff.WithStartTime(ctx.now))
+		},
+		data: func(t file.Task, _ printContext) any { return optionalData(t.ModifiedAt) },
+	},
+	{
+		name: "completed", header: "Completed", optional: true, timestamp: true,
+		value: func(t file.Task, _ printContext) string {
+			if t.CompletedAt.IsZero() {
+				return ""
+			}
+			return t.CompletedAt.Local().Format(completedLayout)
+		},
+		data: func(t file.Task, _ printContext) any { return optionalData(t.CompletedAt) },
+	},
+	{
+		name: "deleted", header: "Deleted", extra: true,
+		value: func(t file.Task, _ printContext) string {
+			if t.DeletedAt.IsZero() {
+				return ""
+			}
+			return t.DeletedAt.Local().Format(completedLayout)
+		},
+		data: func(t file.Task, _ printContext) any { return optionalData(t.DeletedAt) },
+	},
+	{
+		name: "due", header: "Due",
+		value: func(t file.Task, ctx printContext) string { return formatDue(t, ctx.now) },
+		data:  func(t file.Task, _ printContext) any { return optionalData(t.Due) },
+	},
+	{
+		name: "recur", header: "Recur", optional: true,
+		value: func(t file.Task, _ printContext) string { return t.Recur },
+		data:  func(t file.Task, _ printContext) any { return t.Recur },
+	},
+	{
+		name: "urgency", header: "Urg",
+		value: func(t file.Task, ctx printContext) string { return fmt.Sprintf("%.1f", t.Urgency(ctx.now)) },
+		data:  func(t file.Task, ctx printContext) any { return math.Round(t.Urgency(ctx.now)*100) / 100 },
+	},
+	{
+		name: "status", header: "Status",
+		value: func(t file.Task, ctx printContext) string { return string(t.StatusAt(ctx.now)) },
+		data:  func(t file.Task, ctx printContext) any { return string(t.StatusAt(ctx.now)) },
+	},
+}
+
+// Other names --columns accepts
+var columnAliases = map[string]string{
+	"desc":       "description",
+	"task":       "description",
+	"pri":        "priority",
+	"blocked_by": "blocked",
+	"urg":        "urgency",
+}
+
+// Columns named in a comma separated list, like "id,desc,due,tags"
+func parseColumns(spec string) ([]column, error) {
+	var columns []column
+	for name := range strings.SplitSeq(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := columnAliases[name]; ok {
+			name = alias
+		}
+		i := slices.IndexFunc(tableColumns, func(col column) bool { return col.name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("invalid column %q, use one of %s", name, strings.Join(columnNames(), ", "))
+		}
+		columns = append(columns, tableColumns[i])
+	}
+	return columns, nil
+}
+
+func columnNames() []string {
+	names := make([]string, len(tableColumns))
+	for i, col := range tableColumns {
+		names[i] = col.name
+	}
+	return names
+}
+
+// Columns of the default table, before hiding the empty optional ones
+func defaultColumns(ctx printContext) []column {
+	var columns []column
+	for _, col := range tableColumns {
+		if !col.extra && (!col.timestamp || ctx.timestamps) {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// Zero values are written as null by the structured formats
+func optionalData[T comparable](value T) any {
+	var zero T
+	if value == zero {
+		return nil
+	}
+	return value
+}
+
+// Table cells are a single line, tabs would also break the alignment
+var cellReplacer = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+// Header and rows of a table. Without columns, the default ones are used and
+// the optional ones that would be empty are left out
+func tableRows(tasks []file.Task, ctx printContext, columns []column) ([]string, [][]string) {
+	hideEmpty := columns == nil
+	if columns == nil {
+		columns = defaultColumns(ctx)
+	}
+
+	rows := make([][]string, len(tasks))
+	var headers []string
+	for _, col := range columns {
+		values := make([]string, len(tasks))
+		empty := true
+		for i, task := range tasks {
+			values[i] = cellReplacer.Replace(col.value(task, ctx))
+			empty = empty && values[i] == ""
+		}
+		if hideEmpty && col.optional && empty {
+			continue
+		}
+		headers = append(headers, col.header)
+		for i := range tasks {
+			rows[i] = append(rows[i], values[i])
+		}
+	}
+	return headers, rows
+}
+
+// Completion times are shown in full, they are mostly looked at in reports
+const completedLayout = "Mon 2006-01-02 15:04"
+
+// Print tasks as a table with the default columns
+func printTasks(w io.Writer, tasks []file.Task, ctx printContext) error {
+	ctx.now = time.Now()
+	return writeTable(w, tasks, ctx, nil)
+}
+
+func writeTable(w io.Writer, tasks []file.Task, ctx printContext, columns []column) error {
+	tabW := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	headers, rows := tableRows(tasks, ctx, columns)
+
+	if _, err := fmt.Fprintln(tabW, strings.Join(headers, "\t ")); err != nil {
+		return fmt.Errorf("could not write header: %w", err)
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(tabW, strings.Join(row, "\t ")); err != nil {
+			return fmt.Errorf("could not write task: %w", err)
+		}
+	}
+
+	return tabW.Flush()
+}
+
+// Empty for the zero ID of an unset reference
+func formatOptionalID(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+func joinIDs(ids []int) string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	return strings.Join(values, " ")
+}
+
+// Due date relative to now, flagged when the task is overdue
+func formatDue(task file.Task, now time.Time) string {
+	if task.Due.IsZero() {
+		return ""
+	}
+	due := timediff.TimeDiff(task.Due, timediff.WithStartTime(now))
+	if task.Overdue(now) {
+		due += " (overdue)"
+	}
+	return due
+}
+
+// Ask a yes/no question, keep asking until a valid answer is given. Anything
+// that cannot be read (like a closed stdin) counts as a no
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprintf(out, "%s ([y]es | [n]o)? ", question)
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Fprintln(out)
+			return false
+		}
+
+		input = strings.TrimSpace(input)
+
+		if input == "no" || input == "n" {
+			return false
+		}
+
+		if input == "yes" || input == "y" {
+			return true
+		}
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/spf13/cobra"
+)
+
+// projectsCmd represents the projects command
+func newProjectsCmd(storage *file.Repository) *cobra.Command {
+	var all bool
+	projectsCmd := &cobra.Command{
+		Use:   "projects",
+		Short: "list projects and how many tasks they have",
+		Long: `list projects and how many tasks they have
+Tasks in a subproject (like work.backend) also count towards its parents (work).`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("this command does not accept any arguments")
+			}
+
+			tasks, err := (*storage).List(file.Filter{All: all})
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
+			}
+
+			counts := map[string]int{}
+			for _, task := range tasks {
+				if task.Project == "" {
+					continue
+				}
+				parts := strings.Split(task.Project, ".")
+				for i := range parts {
+					counts[strings.Join(parts[:i+1], ".")]++
+				}
+			}
+			return printCounts(cmd.OutOrStdout(), "Project", counts)
+		},
+	}
+
+	projectsCmd.Flags().BoolVarP(&all, "all", "a", false, "Count completed tasks too")
+	return projectsCmd
+}
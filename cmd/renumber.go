@@ -0,0 +1,60 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// renumberCmd represents the renumber command
+func newRenumberCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	renumberCmd := &cobra.Command{
+		Use:   "renumber",
+		Short: "give the tasks consecutive IDs",
+		Long: `give the tasks consecutive IDs from 1, closing the gaps left by deleted and
+archived tasks. Tasks keep their order, and new tasks are numbered after the
+last one.
+
+Parents, dependencies and the history of changes follow the new IDs, so tasks
+undo still works on the changes made before. Every task also has a UUID that
+never changes, see tasks show. Commands that take a task ID also take the start
+of a UUID (8 characters or more), which keeps working after renumbering.
+
+Archived tasks keep the IDs they had when archived, and renumbering skips them
+so they are never given to another task.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("renumber takes no arguments")
+			}
+			cmd.SilenceUsage = true
+
+			archived, err := file.ArchivedIDs(cfg.Filepath, cfg.Storage)
+			if err != nil {
+				return fmt.Errorf("error renumbering tasks: %w", err)
+			}
+			ids, err := (*storage).Renumber(archived)
+			if err != nil {
+				return fmt.Errorf("error renumbering tasks: %w", err)
+			}
+			changed := 0
+			for _, from := range slices.Sorted(maps.Keys(ids)) {
+				if to := ids[from]; to != from {
+					fmt.Fprintf(cmd.OutOrStdout(), "Task %d is now task %d\n", from, to)
+					changed++
+				}
+			}
+			if changed == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "Task IDs are already consecutive")
+			}
+			return nil
+		},
+	}
+	return renumberCmd
+}
@@ -0,0 +1,169 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// The filter of a report narrowed down by the one given on the command line
+func reportFilter(report config.Report, args []string) []string {
+	if strings.TrimSpace(report.Filter) == "" {
+		return args
+	}
+	if len(args) == 0 {
+		return []string{report.Filter}
+	}
+	filter := []string{"(", report.Filter, ")", "("}
+	filter = append(filter, args...)
+	return append(filter, ")")
+}
+
+func runReport(cmd *cobra.Command, storage file.Repository, report config.Report, args []string, format string) error {
+	out := output{format: format}
+	if !slices.Contains(outputFormats, format) {
+		return fmt.Errorf("invalid format %q, use one of %s", format, strings.Join(outputFormats, ", "))
+	}
+	if len(report.Columns) > 0 {
+		var err error
+		if out.columns, err = parseColumns(strings.Join(report.Columns, ",")); err != nil {
+			return fmt.Errorf("invalid report: %w", err)
+		}
+	}
+
+	tasks, err := selectTasks(storage, reportFilter(report, args), false)
+	if err != nil {
+		return fmt.Errorf("error running report: %w", err)
+	}
+	if err := sortTasks(tasks, cmp.Or(report.Sort, "urgency"), time.Now()); err != nil {
+		return fmt.Errorf("invalid report: %w", err)
+	}
+	cmd.SilenceUsage = true
+	if report.Limit > 0 && len(tasks) > report.Limit {
+		tasks = tasks[:report.Limit]
+	}
+
+	if len(tasks) == 0 && out.forPeople() {
+		fmt.Fprintln(cmd.OutOrStdout(), "No tasks found")
+		return nil
+	}
+	blocking, err := blockingTasks(storage)
+	if err != nil {
+		return fmt.Errorf("error running report: %w", err)
+	}
+	ctx := printContext{blocking: blocking}
+	if !out.forPeople() {
+		return writeTasks(cmd.OutOrStdout(), tasks, ctx, out)
+	}
+	if out.format == "table" {
+		fmt.Fprintln(cmd.OutOrStdout(), "Total tasks:", len(tasks))
+	}
+	return writeTasks(cmd.OutOrStdout(), treeOrder(tasks), ctx, out)
+}
+
+// What a report does, for its help and tasks report
+func describeReport(report config.Report) string {
+	var parts []string
+	if report.Filter != "" {
+		parts = append(parts, "filter: "+report.Filter)
+	}
+	if report.Sort != "" {
+		parts = append(parts, "sort: "+report.Sort)
+	}
+	if len(report.Columns) > 0 {
+		parts = append(parts, "columns: "+strings.Join(report.Columns, ","))
+	}
+	if report.Limit > 0 {
+		parts = append(parts, fmt.Sprintf("limit: %d", report.Limit))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reportCmd represents the report command
+func newReportCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	var format string
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "run a report defined in the config file",
+		Long: `list tasks the way a report of the config file says
+tasks report to see the reports
+tasks report <name> to run one
+tasks report <name> <filter> to only look at the tasks of the report that
+  match a filter too
+
+Reports are defined under reports in tasks.yaml, each with a filter, sort keys
+and columns like those of tasks list, and a limit on the number of tasks:
+
+  reports:
+    overdue:
+      description: what should have been done already
+      filter: due.before:now
+      sort: due
+      columns: [id, description, due, tags]
+    today:
+      filter: "due.before:tomorrow or status:started"
+      limit: 10
+
+Reports whose name is not taken by another command can also be run as
+tasks <name>.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				cmd.SilenceUsage = true
+				if len(cfg.Reports) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "No reports defined, add them to the config file under reports")
+					return nil
+				}
+				tabW := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 2, 2, ' ', 0)
+				fmt.Fprintln(tabW, "Report\t Description")
+				for _, name := range slices.Sorted(maps.Keys(cfg.Reports)) {
+					report := cfg.Reports[name]
+					fmt.Fprintf(tabW, "%s\t %s\n", name, cmp.Or(report.Description, describeReport(report)))
+				}
+				return tabW.Flush()
+			}
+
+			report, ok := cfg.Reports[args[0]]
+			if !ok {
+				return fmt.Errorf("no report named %q, run tasks report to see them", args[0])
+			}
+			return runReport(cmd, *storage, report, args[1:], format)
+		},
+	}
+	reportCmd.Flags().StringVar(&format, "format", "table", "output format: "+strings.Join(outputFormats, ", "))
+	return reportCmd
+}
+
+// Run reports as tasks <name>, except those named like another command
+func addReportCmds(rootCmd *cobra.Command, cfg *config.Config, storage *file.Repository) {
+	for _, name := range slices.Sorted(maps.Keys(cfg.Reports)) {
+		if cmd, _, err := rootCmd.Find([]string{name}); err == nil && cmd != rootCmd {
+			continue
+		}
+		report := cfg.Reports[name]
+		var format string
+		reportCmd := &cobra.Command{
+			Use:   name,
+			Short: cmp.Or(report.Description, "report defined in the config file"),
+			Long: fmt.Sprintf(`run the %s report of the config file, same as tasks report %s
+tasks %s <filter> to only look at the tasks of the report that match a filter too
+
+%s`, name, name, name, describeReport(report)),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runReport(cmd, *storage, report, args, format)
+			},
+		}
+		reportCmd.Flags().StringVar(&format, "format", "table", "output format: "+strings.Join(outputFormats, ", "))
+		rootCmd.AddCommand(reportCmd)
+	}
+}
@@ -17,6 +17,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/MoXcz/tasks/file"
 	"github.com/MoXcz/tasks/internal/config"
@@ -24,16 +25,37 @@ import (
 )
 
 func NewRootCmd(cfg config.Config) *cobra.Command {
-	var storage file.FileStorage
+	var storage file.Repository
+	var journal *file.Journal
 	rootCmd := &cobra.Command{
 		Use:   "tasks",
 		Short: "A task management CLI that tries to mimic the common TODO web application",
 		Long: `tasks is a CLI tool to manage your tasks, that's it.
 
 	tasks add <task> to add a new task
+	tasks modify <task id> to change a task
+	tasks edit <task id> to change a task in your editor
+	tasks annotate <task id> <note> to add a note to a task
+	tasks show <task id> to see a task in full
 	tasks list to list all tasks
+	tasks next to list the tasks that can be started right away
+	tasks search <text> to find tasks, completed ones included
+	tasks report <name> to run a report defined in the config file
+	tasks tags / projects to see how tasks are organized
 	tasks complete <task id> to mark a task as completed
-	tasks delete <task id> to delete a task
+	tasks done --since 7d to see what was completed lately
+	tasks wait / block / cancel / reopen <task id> to change its status
+	tasks start / stop <task id> to track the time spent on a task
+	tasks timesheet --week to see where the time went
+	tasks delete <task id> to move a task to the trash
+	tasks trash / restore <task id> / purge to see, bring back or remove deleted tasks
+	tasks archive --older-than 30d to move old completed tasks out of the way
+	tasks renumber to close the gaps between task IDs
+	tasks undo / redo to revert or make again the last changes, tasks log to see them
+	(list, modify, complete and delete also take filters like "+bug or 1-3")
+	(a task ID can also be given as the start of the task's UUID, see tasks show)
+	tasks export / import to move tasks between files and formats
+	tasks migrate --to <storage> to change the storage type
 `,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			var err error
@@ -43,16 +65,48 @@ func NewRootCmd(cfg config.Config) *cobra.Command {
 				// This could also happen when the config file and the tasks are on the same dir
 				return fmt.Errorf("error selecting storage: %w", err)
 			}
+			// Every change a command makes can be undone
+			journal = file.NewJournal(file.JournalPath(cfg.Filepath, cfg.Storage))
+			storage = journal.Record(storage, strings.Join(append([]string{cmd.Name()}, args...), " "))
 			return nil
 		},
 	}
 	rootCmd.PersistentFlags().StringVar(&cfg.Filepath, "config", cfg.Filepath, "config file")
-	rootCmd.PersistentFlags().StringVar(&cfg.Storage, "storage", cfg.Storage, "storage type")
+	rootCmd.PersistentFlags().StringVar(&cfg.Storage, "storage", cfg.Storage, "storage type (csv, json or sqlite)")
 
-	rootCmd.AddCommand(newListCmd(&storage))
-	rootCmd.AddCommand(newDeleteCmd(&storage))
-	rootCmd.AddCommand(newCompleteCmd(&storage))
+	rootCmd.AddCommand(newListCmd(&cfg, &storage))
+	rootCmd.AddCommand(newDeleteCmd(&cfg, &storage))
+	rootCmd.AddCommand(newTrashCmd(&storage))
+	rootCmd.AddCommand(newRestoreCmd(&storage))
+	rootCmd.AddCommand(newPurgeCmd(&storage))
+	rootCmd.AddCommand(newArchiveCmd(&cfg, &storage))
+	rootCmd.AddCommand(newRenumberCmd(&cfg, &storage))
+	rootCmd.AddCommand(newCompleteCmd(&cfg, &storage))
+	rootCmd.AddCommand(newDoneCmd(&storage))
+	rootCmd.AddCommand(newStartCmd(&cfg, &storage))
+	rootCmd.AddCommand(newStopCmd(&storage))
+	rootCmd.AddCommand(newTimesheetCmd(&storage))
+	rootCmd.AddCommand(newWaitCmd(&cfg, &storage))
+	rootCmd.AddCommand(newBlockCmd(&cfg, &storage))
+	rootCmd.AddCommand(newCancelCmd(&cfg, &storage))
+	rootCmd.AddCommand(newReopenCmd(&cfg, &storage))
 	rootCmd.AddCommand(newAddCmd(&storage))
+	rootCmd.AddCommand(newModifyCmd(&cfg, &storage))
+	rootCmd.AddCommand(newEditCmd(&storage))
+	rootCmd.AddCommand(newAnnotateCmd(&storage))
+	rootCmd.AddCommand(newShowCmd(&storage))
+	rootCmd.AddCommand(newNextCmd(&storage))
+	rootCmd.AddCommand(newSearchCmd(&storage))
+	rootCmd.AddCommand(newTagsCmd(&storage))
+	rootCmd.AddCommand(newProjectsCmd(&storage))
+	rootCmd.AddCommand(newExportCmd(&storage))
+	rootCmd.AddCommand(newImportCmd(&storage))
+	rootCmd.AddCommand(newMigrateCmd(&cfg, &storage))
+	rootCmd.AddCommand(newUndoCmd(&storage, &journal))
+	rootCmd.AddCommand(newRedoCmd(&storage, &journal))
+	rootCmd.AddCommand(newLogCmd(&storage, &journal))
+	rootCmd.AddCommand(newReportCmd(&cfg, &storage))
+	addReportCmds(rootCmd, &cfg, &storage)
 
 	return rootCmd
 }
@@ -2,12 +2,21 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/MoXcz/tasks/file"
 	"github.com/MoXcz/tasks/internal/config"
+	"github.com/MoXcz/tasks/internal/dates"
 )
 
 func TestCLIIntegration(t *testing.T) {
@@ -33,6 +42,14 @@ func TestCLIIntegration(t *testing.T) {
 				Storage:  "json",
 			},
 		},
+		{
+			name:  "SQLite cmd",
+			DoAll: true,
+			cfg: config.Config{
+				Filepath: filepath.Join(dir, "tasks"),
+				Storage:  "sqlite",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -112,9 +129,1640 @@ func TestCLIIntegration(t *testing.T) {
 
 }
 
+func TestIDsAreNotReused(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "first"},
+				{"add", "second"},
+				{"delete", "2", "--force"},
+				{"add", "third"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			for _, want := range []string{"1    first", "3    third"} {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("expected %q in list, got %q", want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestExportImportMigrate(t *testing.T) {
+	dir := t.TempDir()
+	csvCfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: "csv"}
+	buf := &bytes.Buffer{}
+	for _, args := range [][]string{
+		{"add", "Rice linux"},
+		{"add", "Configure Neovim"},
+		{"complete", "1"},
+	} {
+		if err := runCommand(csvCfg, buf, args...); err != nil {
+			t.Fatalf("%s command failed: %v", args[0], err)
+		}
+	}
+
+	exported := filepath.Join(dir, "export.json")
+	if err := runCommand(csvCfg, buf, "export", "--output", exported); err != nil {
+		t.Fatalf("export command failed: %v", err)
+	}
+
+	// Merging into a store that already has the tasks only adds new ones
+	jsonCfg := config.Config{Filepath: filepath.Join(dir, "other"), Storage: "json"}
+	if err := runCommand(jsonCfg, buf, "add", "Water plants"); err != nil {
+		t.Fatalf("add command failed: %v", err)
+	}
+	buf.Reset()
+	if err := runCommand(jsonCfg, buf, "import", exported); err != nil {
+		t.Fatalf("import command failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "task 1 imported as 2") {
+		t.Errorf("expected remapped ID, got %q", buf.String())
+	}
+	buf.Reset()
+	if err := runCommand(jsonCfg, buf, "import", exported); err != nil {
+		t.Fatalf("import command failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Imported 0 tasks") || !strings.Contains(buf.String(), "Skipped 2 duplicate tasks") {
+		t.Errorf("expected duplicates to be skipped, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := runCommand(jsonCfg, buf, "list", "--all"); err != nil {
+		t.Fatalf("list command failed: %v", err)
+	}
+	for _, want := range []string{"1    Water plants", "2    Rice linux", "3    Configure Neovim"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in list, got %q", want, buf.String())
+		}
+	}
+
+	// Migrating keeps IDs and completion state
+	buf.Reset()
+	if err := runCommand(csvCfg, buf, "migrate", "--to", "sqlite"); err != nil {
+		t.Fatalf("migrate command failed: %v", err)
+	}
+	sqliteCfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: "sqlite"}
+	buf.Reset()
+	if err := runCommand(sqliteCfg, buf, "list"); err != nil {
+		t.Fatalf("list command failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "Rice linux") || !strings.Contains(buf.String(), "2    Configure Neovim") {
+		t.Errorf("expected only the pending task with its ID, got %q", buf.String())
+	}
+}
+
+func TestDueDates(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			if err := runCommand(cfg, buf, "add", "Write report", "--due", "+3d"); err != nil {
+				t.Fatalf("add command failed: %v", err)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "in 3 days") {
+				t.Errorf("expected relative due date in list, got %q", buf.String())
+			}
+
+			if err := runCommand(cfg, buf, "modify", "1", "--due", "-3d"); err != nil {
+				t.Fatalf("modify command failed: %v", err)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "days ago (overdue)") {
+				t.Errorf("expected overdue task in list, got %q", buf.String())
+			}
+
+			if err := runCommand(cfg, buf, "modify", "1", "--due", "none"); err != nil {
+				t.Fatalf("modify command failed: %v", err)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if strings.Contains(buf.String(), "overdue") {
+				t.Errorf("expected due date to be removed, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestListSort(t *testing.T) {
+	cfg := config.Config{Filepath: filepath.Join(t.TempDir(), "tasks"), Storage: "json"}
+	buf := &bytes.Buffer{}
+	for _, args := range [][]string{
+		{"add", "Someday", "--priority", "L"},
+		{"add", "Urgent fix", "--priority", "H", "--due", "today"},
+		{"add", "Chores", "--priority", "M"},
+	} {
+		if err := runCommand(cfg, buf, args...); err != nil {
+			t.Fatalf("add command failed: %v", err)
+		}
+	}
+
+	order := func(args ...string) []string {
+		buf.Reset()
+		if err := runCommand(cfg, buf, append([]string{"list"}, args...)...); err != nil {
+			t.Fatalf("list command failed: %v", err)
+		}
+		var ids []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n")[2:] {
+			ids = append(ids, strings.Fields(line)[0])
+		}
+		return ids
+	}
+
+	if got := strings.Join(order(), ","); got != "2,3,1" {
+		t.Errorf("expected tasks sorted by urgency, got %s", got)
+	}
+	if got := strings.Join(order("--sort", "id"), ","); got != "1,2,3" {
+		t.Errorf("expected tasks sorted by ID, got %s", got)
+	}
+	if got := strings.Join(order("--sort", "-priority"), ","); got != "1,3,2" {
+		t.Errorf("expected tasks sorted by lowest priority, got %s", got)
+	}
+	if err := runCommand(cfg, buf, "list", "--sort", "colour"); err == nil {
+		t.Error("expected an invalid sort key to fail")
+	}
+}
+
+func TestTagsAndProjects(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Fix login", "+bug", "project:work.backend"},
+				{"add", "Review", "PR", "+review", "--project", "work"},
+				{"add", "Buy milk", "--tag", "errand", "-t", "bug"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("add command failed: %v", err)
+				}
+			}
+
+			list := func(args ...string) string {
+				buf.Reset()
+				if err := runCommand(cfg, buf, append([]string{"list"}, args...)...); err != nil {
+					t.Fatalf("list command failed: %v", err)
+				}
+				return buf.String()
+			}
+
+			got := list("project:work")
+			if !strings.Contains(got, "Fix login") || !strings.Contains(got, "Review PR") || strings.Contains(got, "Buy milk") {
+				t.Errorf("expected only work tasks, got %q", got)
+			}
+			if !strings.Contains(got, "work.backend") {
+				t.Errorf("expected project column, got %q", got)
+			}
+			got = list("+bug", "--", "-errand")
+			if !strings.Contains(got, "Fix login") || strings.Contains(got, "Buy milk") {
+				t.Errorf("expected only bugs that are not errands, got %q", got)
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "tags"); err != nil {
+				t.Fatalf("tags command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "bug      2") {
+				t.Errorf("expected tag counts, got %q", buf.String())
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "projects"); err != nil {
+				t.Fatalf("projects command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "work           2") || !strings.Contains(buf.String(), "work.backend   1") {
+				t.Errorf("expected project counts, got %q", buf.String())
+			}
+		})
+	}
+}
+
+// A quoted description is kept as it is, words in it that look like tags or
+// projects included
+func TestQuotedDescription(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Temperature is -5 degrees"},
+				{"add", "Call +1  555", "+phone"},
+				{"add", "Pay\tproject:rent depends:1"},
+				{"modify", "1", "Set it to -3 degrees", "+home"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "--sort", "id", "--format", "json", "--columns", "id,description,tags,project,depends"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			var got []map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			want := []map[string]any{
+				{"id": 1.0, "description": "Set it to -3 degrees", "tags": []any{"home"}},
+				{"id": 2.0, "description": "Call +1  555", "tags": []any{"phone"}},
+				{"id": 3.0, "description": "Pay\tproject:rent depends:1"},
+			}
+			if len(got) != len(want) {
+				t.Fatalf("list = %q, want 3 tasks", buf.String())
+			}
+			for i := range want {
+				for key, value := range want[i] {
+					if !reflect.DeepEqual(got[i][key], value) {
+						t.Errorf("task %d %s = %#v, want %#v", i+1, key, got[i][key], value)
+					}
+				}
+				if got[i]["project"] != "" || fmt.Sprint(got[i]["depends"]) != "[]" {
+					t.Errorf("task %d = %#v, want no project or dependencies", i+1, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestModifyAndEdit(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Rice linux", "+fun", "+home", "project:desktop"},
+				{"modify", "1", "Rice", "arch", "+weekend", "project:", "--priority", "H", "--", "-fun"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "+home", "+weekend"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			got := buf.String()
+			if !strings.Contains(got, "Rice arch") || !strings.Contains(got, "H") || strings.Contains(got, "fun") || strings.Contains(got, "desktop") {
+				t.Errorf("expected modified task, got %q", got)
+			}
+
+			if err := runCommand(cfg, buf, "modify", "1"); err == nil {
+				t.Error("expected modify without changes to fail")
+			}
+
+			t.Setenv("VISUAL", "")
+			t.Setenv("EDITOR", "sed -i -e s/arch/gentoo/ -e s/^priority:.*/priority:/")
+			if err := runCommand(cfg, buf, "edit", "1"); err != nil {
+				t.Fatalf("edit command failed: %v", err)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			got = buf.String()
+			if !strings.Contains(got, "Rice gentoo") || strings.Contains(got, "  H  ") || !strings.Contains(got, "home weekend") {
+				t.Errorf("expected edited task, got %q", got)
+			}
+
+			// Invalid edits are not saved, answering no to editing again
+			t.Setenv("EDITOR", "sed -i s/^priority:.*/priority: urgent/")
+			if err := runCommandWithInput(cfg, buf, "n\n", "edit", "1"); err == nil {
+				t.Error("expected an invalid edit to fail")
+			}
+			// The status is left to the commands that change it
+			editor := filepath.Join(t.TempDir(), "editor.sh")
+			if err := os.WriteFile(editor, []byte("echo 'status: done' >> \"$1\"\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("EDITOR", "sh "+editor)
+			buf.Reset()
+			if err := runCommandWithInput(cfg, buf, "n\n", "edit", "1"); err == nil || !strings.Contains(buf.String(), "field status not found") {
+				t.Errorf("edit of the status = %v, %q, want it rejected", err, buf.String())
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "status:pending", "--format", "csv", "--columns", "id"); err != nil || buf.String() != "id\n1\n" {
+				t.Errorf("list status:pending after editing the status = %q, %v, want task 1", buf.String(), err)
+			}
+
+			// The parent and the dependencies can be edited, and are checked
+			if err := runCommand(cfg, buf, "add", "Install the desktop"); err != nil {
+				t.Fatalf("add command failed: %v", err)
+			}
+			t.Setenv("EDITOR", "sed -i -e s/^parent:.*/parent:\\x202/ -e s/^depends:.*/depends:\\x20[2]/")
+			if err := runCommand(cfg, buf, "edit", "1"); err != nil {
+				t.Fatalf("edit command failed: %v", err)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "1", "--format", "csv", "--columns", "id,parent,depends"); err != nil || buf.String() != "id,parent,depends\n1,2,2\n" {
+				t.Errorf("list after editing the parent = %q, %v, want task 1 under task 2", buf.String(), err)
+			}
+			t.Setenv("EDITOR", "sed -i s/^parent:.*/parent:\\x201/")
+			buf.Reset()
+			if err := runCommandWithInput(cfg, buf, "n\n", "edit", "1"); err == nil || !strings.Contains(buf.String(), "subtask of itself") {
+				t.Errorf("edit making the task its own parent = %v, %q, want it rejected", err, buf.String())
+			}
+
+			// Changes made while the editor is open are kept, unless they touch
+			// the edited fields
+			editDir := t.TempDir()
+			editor = filepath.Join(editDir, "editor.sh")
+			script := fmt.Sprintf("touch %[1]s/ready\nwhile [ ! -f %[1]s/go ]; do sleep 0.01; done\nrm %[1]s/ready %[1]s/go\nsed -i s/gentoo/void/ \"$1\"\n", editDir)
+			if err := os.WriteFile(editor, []byte(script), 0600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("EDITOR", "sh "+editor)
+			editWhile := func(args ...string) (string, error) {
+				t.Helper()
+				out := &bytes.Buffer{}
+				done := make(chan error)
+				go func() { done <- runCommandWithInput(cfg, out, "n\n", "edit", "1") }()
+				for {
+					if _, err := os.Stat(filepath.Join(editDir, "ready")); err == nil {
+						break
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+				if err := runCommand(cfg, io.Discard, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+				if err := os.WriteFile(filepath.Join(editDir, "go"), nil, 0600); err != nil {
+					t.Fatal(err)
+				}
+				return out.String(), <-done
+			}
+			if out, err := editWhile("annotate", "1", "ask about drivers"); err != nil {
+				t.Fatalf("edit command failed: %v, %q", err, out)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "show", "1"); err != nil {
+				t.Fatalf("show command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Rice void") || !strings.Contains(got, "ask about drivers") {
+				t.Errorf("show after annotating during the edit = %q, want both changes", got)
+			}
+			if _, err := editWhile("modify", "1", "Rice nixos"); err == nil || !strings.Contains(err.Error(), "changed while it was being edited") {
+				t.Errorf("edit of a task modified meanwhile = %v, want it refused", err)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "1", "--format", "csv", "--columns", "description"); err != nil || buf.String() != "description\nRice nixos\n" {
+				t.Errorf("list after a refused edit = %q, %v, want the modification kept", buf.String(), err)
+			}
+		})
+	}
+}
+
 func runCommand(cfg config.Config, out io.Writer, args ...string) error {
+	return runCommandWithInput(cfg, out, "", args...)
+}
+
+func runCommandWithInput(cfg config.Config, out io.Writer, in string, args ...string) error {
 	cmd := NewRootCmd(cfg)
+	cmd.SetIn(strings.NewReader(in))
 	cmd.SetOut(out)
+	cmd.SetErr(out)
 	cmd.SetArgs(args)
 	return cmd.Execute()
 }
+
+func TestFilterAndBulkChanges(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage, ConfirmThreshold: 2}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Fix login", "+bug", "project:work"},
+				{"add", "Review PR", "project:work"},
+				{"add", "Buy milk", "+errand"},
+				{"add", "Call mom", "project:home", "--due", "tomorrow"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("add command failed: %v", err)
+				}
+			}
+
+			list := func(args ...string) string {
+				buf.Reset()
+				if err := runCommand(cfg, buf, append([]string{"list"}, args...)...); err != nil {
+					t.Fatalf("list command failed: %v", err)
+				}
+				return buf.String()
+			}
+
+			got := list("(+bug or +errand) or due.before:+2d")
+			if !strings.Contains(got, "Total tasks: 3") || strings.Contains(got, "Review PR") {
+				t.Errorf("expected tasks matching the filter, got %q", got)
+			}
+
+			// Above the threshold a preview is shown and nothing changes without a yes
+			buf.Reset()
+			if err := runCommandWithInput(cfg, buf, "n\n", "complete", "1-3"); err != nil {
+				t.Fatalf("complete command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "Buy milk") || !strings.Contains(list(), "Total tasks: 4") {
+				t.Errorf("expected a preview and no completed tasks, got %q", buf.String())
+			}
+			if err := runCommandWithInput(cfg, buf, "y\n", "complete", "1-3"); err != nil {
+				t.Fatalf("complete command failed: %v", err)
+			}
+			if got := list(); !strings.Contains(got, "Total tasks: 1") || !strings.Contains(got, "Call mom") {
+				t.Errorf("expected only one pending task, got %q", got)
+			}
+
+			for _, args := range [][]string{
+				{"modify", "status:completed and (+errand or +review)", "+keep"},
+				{"delete", "status:completed and not +keep", "--force"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+			got = list("--all")
+			if !strings.Contains(got, "Total tasks: 2") || strings.Contains(got, "Fix login") || !strings.Contains(got, "Buy milk") {
+				t.Errorf("expected completed tasks without the keep tag to be deleted, got %q", got)
+			}
+
+			// Modify only looks at pending tasks unless told otherwise
+			if err := runCommand(cfg, buf, "modify", "+keep", "+done"); err == nil {
+				t.Error("expected modify without matching tasks to fail")
+			}
+			if err := runCommand(cfg, buf, "list", "(+bug"); err == nil {
+				t.Error("expected an invalid filter to fail")
+			}
+		})
+	}
+}
+
+func TestRecurringTasks(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			if err := runCommand(cfg, buf, "add", "Weekly report", "--recur", "weekly", "--due", "+1d"); err != nil {
+				t.Fatalf("add command failed: %v", err)
+			}
+			if err := runCommand(cfg, buf, "add", "Nap", "--recur", "hourly"); err != nil {
+				t.Fatalf("add command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "Invalid recurrence") {
+				t.Errorf("expected an invalid recurrence error, got %q", buf.String())
+			}
+
+			// The template is hidden, only its first instance is listed
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Total tasks: 1") || !strings.Contains(got, "weekly") {
+				t.Errorf("expected one recurring task, got %q", got)
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "complete", "2"); err != nil {
+				t.Fatalf("complete command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "Next occurrence: task 3") {
+				t.Errorf("expected the next occurrence, got %q", buf.String())
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Total tasks: 1") || !strings.Contains(got, "in 8 days") {
+				t.Errorf("expected the next instance due a week later, got %q", got)
+			}
+
+			// Deleting the template stops the recurrence
+			buf.Reset()
+			for _, args := range [][]string{
+				{"delete", "status:recurring", "--force"},
+				{"complete", "3"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+			if strings.Contains(buf.String(), "Next occurrence") {
+				t.Errorf("expected no next occurrence, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestSubtasks(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Release v2"},
+				{"add", "Write tests", "--parent", "1"},
+				{"add", "Unit tests", "--parent", "2"},
+				{"add", "Update docs", "--parent", "1"},
+				{"add", "Unrelated"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("add command failed: %v", err)
+				}
+			}
+			if err := runCommand(cfg, buf, "add", "Orphan", "--parent", "42"); err != nil {
+				t.Fatalf("add command failed: %v", err)
+			}
+			if err := runCommand(cfg, buf, "modify", "1", "--parent", "3"); err == nil {
+				t.Error("expected a parent loop to be rejected")
+			}
+
+			list := func() string {
+				buf.Reset()
+				if err := runCommand(cfg, buf, "list", "--sort", "id"); err != nil {
+					t.Fatalf("list command failed: %v", err)
+				}
+				return buf.String()
+			}
+			got := list()
+			for _, want := range []string{"1    Release v2", "2    └ Write tests", "3      └ Unit tests", "4    └ Update docs", "5    Unrelated"} {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in the tree, got %q", want, got)
+				}
+			}
+			if strings.Contains(got, "Orphan") {
+				t.Errorf("expected a subtask of a missing task to be rejected, got %q", got)
+			}
+
+			// Declining to complete the subtasks keeps the parent pending
+			buf.Reset()
+			if err := runCommandWithInput(cfg, buf, "n\n", "complete", "2"); err != nil {
+				t.Fatalf("complete command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "Not completing task 2") || !strings.Contains(list(), "Total tasks: 5") {
+				t.Errorf("expected task 2 to stay pending, got %q", buf.String())
+			}
+			if err := runCommandWithInput(cfg, buf, "y\n", "complete", "2"); err != nil {
+				t.Fatalf("complete command failed: %v", err)
+			}
+			if got := list(); !strings.Contains(got, "Total tasks: 3") || strings.Contains(got, "Unit tests") {
+				t.Errorf("expected task 2 and its subtask to be completed, got %q", got)
+			}
+
+			// Subtasks of a deleted task move up, or are deleted along with it
+			buf.Reset()
+			if err := runCommand(cfg, buf, "delete", "2", "--force"); err != nil {
+				t.Fatalf("delete command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "Moved subtask 3 under task 1") {
+				t.Errorf("expected subtask 3 to move under task 1, got %q", buf.String())
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "delete", "1", "--force", "--subtasks", "delete"); err != nil {
+				t.Fatalf("delete command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "Deleting subtask: Unit tests") || !strings.Contains(buf.String(), "Deleting subtask: Update docs") {
+				t.Errorf("expected the subtasks to be deleted, got %q", buf.String())
+			}
+			if got := list(); !strings.Contains(got, "Total tasks: 1") || !strings.Contains(got, "Unrelated") {
+				t.Errorf("expected only the unrelated task, got %q", got)
+			}
+		})
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Build image"},
+				{"add", "Run migrations"},
+				{"add", "Roll out", "depends:1,2"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("add command failed: %v", err)
+				}
+			}
+			if err := runCommand(cfg, buf, "modify", "1", "depends:3"); err == nil || !strings.Contains(err.Error(), "cycle") {
+				t.Errorf("expected a dependency cycle error, got %v", err)
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "--sort", "id"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Blocked by") || !strings.Contains(got, "1 2") {
+				t.Errorf("expected task 3 to be blocked by 1 and 2, got %q", got)
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "next"); err != nil {
+				t.Fatalf("next command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Build image") || strings.Contains(got, "Roll out") {
+				t.Errorf("expected only unblocked tasks, got %q", got)
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "complete", "3"); err != nil {
+				t.Fatalf("complete command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "Warning: task 3 is blocked by pending tasks 1 2") {
+				t.Errorf("expected a blocked warning, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestStatuses(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Write report"},
+				{"add", "Renew passport"},
+				{"add", "Buy a boat"},
+				{"add", "Fix the fence"},
+				{"start", "1"},
+				{"wait", "2", "--until", "+3d"},
+				{"cancel", "3"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+			if got := buf.String(); !strings.Contains(got, "Started task 1: Write report") ||
+				!strings.Contains(got, "Task 2 is waiting until") || !strings.Contains(got, "Cancelled task 3: Buy a boat") {
+				t.Errorf("expected the status changes, got %q", got)
+			}
+
+			// Waiting and cancelled tasks are hidden, the started one comes first
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "--sort", "status"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			got := buf.String()
+			if strings.Contains(got, "Renew passport") || strings.Contains(got, "Buy a boat") ||
+				strings.Index(got, "Write report") > strings.Index(got, "Fix the fence") || !strings.Contains(got, "started") {
+				t.Errorf("expected the started task before the pending one, got %q", got)
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "status:waiting"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Renew passport") || strings.Contains(got, "Write report") {
+				t.Errorf("expected only the waiting task, got %q", got)
+			}
+
+			// Closed tasks cannot be blocked or started, but they can be reopened
+			buf.Reset()
+			if err := runCommand(cfg, buf, "block", "3"); err != nil {
+				t.Fatalf("block command failed: %v", err)
+			}
+			if err := runCommand(cfg, buf, "start", "3"); err == nil {
+				t.Error("expected starting a cancelled task to fail")
+			}
+			if !strings.Contains(buf.String(), "Skipping task 3, it is cancelled") {
+				t.Errorf("expected cancelled task to be skipped, got %q", buf.String())
+			}
+			if err := runCommand(cfg, buf, "reopen", "2,3"); err != nil {
+				t.Fatalf("reopen command failed: %v", err)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "status:pending"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Renew passport") || !strings.Contains(got, "Buy a boat") {
+				t.Errorf("expected reopened tasks to be pending, got %q", got)
+			}
+		})
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Write report"},
+				{"add", "Review PR"},
+				{"add", "Plan sprint"},
+				{"add", "Update docs"},
+				{"complete", "2"},
+				{"complete", "1"},
+				{"complete", "3"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+
+			// Pretend task 3 was completed a month ago and task 2 an hour ago,
+			// CSV only keeps seconds
+			repo, err := file.SelectStorage(cfg.Filepath, storage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for id, completed := range map[int]time.Time{3: time.Now().AddDate(0, -1, 0), 2: time.Now().Add(-time.Hour)} {
+				task, err := repo.Get(id)
+				if err != nil {
+					t.Fatalf("Get() failed: %v", err)
+				}
+				if task.CompletedAt.IsZero() || task.ModifiedAt.Before(task.CreatedAt) {
+					t.Errorf("Get() = %+v, want completion and modification times", task)
+				}
+				task.CompletedAt = completed
+				if _, err := repo.Update(task); err != nil {
+					t.Fatalf("Update() failed: %v", err)
+				}
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "done", "--since", "7d"); err != nil {
+				t.Fatalf("done command failed: %v", err)
+			}
+			got := buf.String()
+			if !strings.Contains(got, "Completed 2 tasks") || strings.Contains(got, "Plan sprint") ||
+				strings.Index(got, "Review PR") > strings.Index(got, "Write report") {
+				t.Errorf("expected tasks 2 and 1 in completion order, got %q", got)
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "--all"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Modified") || !strings.Contains(got, "Completed") {
+				t.Errorf("expected timestamps in list --all, got %q", got)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if got := buf.String(); strings.Contains(got, "Modified") {
+				t.Errorf("expected no timestamps in list, got %q", got)
+			}
+		})
+	}
+}
+
+func TestTimeTracking(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Write report", "+work"},
+				{"add", "Fix login", "+work", "+bug"},
+				{"start", "1"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+			if err := runCommand(cfg, buf, "start", "2"); err == nil || !strings.Contains(err.Error(), "task 1 is started") {
+				t.Errorf("expected starting a second task to fail, got %v", err)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "start", "2", "--auto-stop"); err != nil {
+				t.Fatalf("start command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Stopped task 1: Write report") || !strings.Contains(got, "Started task 2: Fix login") {
+				t.Errorf("expected task 1 to be stopped and task 2 started, got %q", got)
+			}
+			if err := runCommand(cfg, buf, "stop"); err != nil {
+				t.Fatalf("stop command failed: %v", err)
+			}
+			if err := runCommand(cfg, buf, "stop"); err == nil {
+				t.Error("expected stop without a started task to fail")
+			}
+
+			// --week is the current week, which is also the default
+			buf.Reset()
+			if err := runCommand(cfg, buf, "timesheet", "--week"); err != nil {
+				t.Fatalf("timesheet --week command failed: %v", err)
+			}
+			week := buf.String()
+			monday, _ := dates.Since("mon", time.Now())
+			if want := monday.Format(completedLayout); !strings.Contains(week, want) {
+				t.Errorf("timesheet --week = %q, want it since %s", week, want)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "timesheet"); err != nil {
+				t.Fatalf("timesheet command failed: %v", err)
+			}
+			if got := buf.String(); got != week {
+				t.Errorf("timesheet = %q, want the same as timesheet --week %q", got, week)
+			}
+			for _, period := range [][]string{{"--since", "2w"}, {"--until", "yesterday"}} {
+				if err := runCommand(cfg, buf, append([]string{"timesheet", "--week"}, period...)...); err == nil || !strings.Contains(err.Error(), "--week cannot be combined") {
+					t.Errorf("timesheet --week %s error = %v, want it rejected", period[0], err)
+				}
+			}
+
+			// Replace the tracked seconds with known intervals last week, the
+			// first one going past midnight
+			repo, err := file.SelectStorage(cfg.Filepath, storage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			monday = monday.AddDate(0, 0, -7)
+			period := []string{"--since", monday.Format(time.DateOnly), "--until", monday.AddDate(0, 0, 6).Format(time.DateOnly)}
+			for id, intervals := range map[int][]file.Interval{
+				1: {{Start: monday.Add(23 * time.Hour), End: monday.Add(26 * time.Hour)}},
+				2: {{Start: monday.Add(-time.Hour), End: monday.Add(time.Hour)}},
+			} {
+				task, err := repo.Get(id)
+				if err != nil {
+					t.Fatalf("Get() failed: %v", err)
+				}
+				task.Intervals = intervals
+				if _, err := repo.Update(task); err != nil {
+					t.Fatalf("Update() failed: %v", err)
+				}
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, append([]string{"timesheet"}, period...)...); err != nil {
+				t.Fatalf("timesheet command failed: %v", err)
+			}
+			got := buf.String()
+			for _, want := range []string{"4.00 hours", "Write report   3.00", "Fix login      1.00", "bug    1.00", "work   4.00", monday.Format("Mon 2006-01-02") + "   2.00"} {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in timesheet, got %q", want, got)
+				}
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, append([]string{"timesheet", "--format", "csv"}, period...)...); err != nil {
+				t.Fatalf("timesheet command failed: %v", err)
+			}
+			want := "Date,ID,Task,Project,Tags,Hours\n" +
+				monday.Format(time.DateOnly) + ",1,Write report,,work,1.00\n" +
+				monday.Format(time.DateOnly) + ",2,Fix login,,bug work,1.00\n" +
+				monday.AddDate(0, 0, 1).Format(time.DateOnly) + ",1,Write report,,work,2.00\n"
+			if got := buf.String(); got != want {
+				t.Errorf("timesheet --format csv = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	// An editor that writes a two line note
+	editor := filepath.Join(dir, "editor.sh")
+	if err := os.WriteFile(editor, []byte("printf 'line one, with a comma\\nline two\\n' >> \"$1\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sh "+editor)
+
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Renew passport"},
+				{"annotate", "1", "called", "the embassy"},
+				{"annotate", "1"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+			if !strings.Contains(buf.String(), "Annotated task 1: Renew passport") {
+				t.Errorf("expected the task to be annotated, got %q", buf.String())
+			}
+			if err := runCommand(cfg, buf, "annotate", "42", "nope"); err == nil {
+				t.Error("expected annotating a missing task to fail")
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "show", "1"); err != nil {
+				t.Fatalf("show command failed: %v", err)
+			}
+			got := buf.String()
+			if !strings.Contains(got, "Renew passport") || !strings.Contains(got, "  called the embassy\n") ||
+				!strings.Contains(got, "  line one, with a comma\n  line two\n") || strings.Contains(got, "#") {
+				t.Errorf("expected the task with its notes, got %q", got)
+			}
+		})
+	}
+}
+
+func TestShow(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Release v2", "+work", "project:app", "--due", "+2d", "--priority", "H"},
+				{"add", "Write tests", "depends:1", "--parent", "1"},
+				{"start", "2"},
+				{"annotate", "2", "unit tests first"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "show", "2"); err != nil {
+				t.Fatalf("show command failed: %v", err)
+			}
+			got := buf.String()
+			for _, want := range []string{"Write tests", "started since", "Parent      1", "Blocked by  1", "Time spent  0m, running", "Status history", "unit tests first"} {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in show, got %q", want, got)
+				}
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "show", "1", "--format", "json"); err != nil {
+				t.Fatalf("show command failed: %v", err)
+			}
+			// Not embedding file.Task, its UnmarshalJSON would take over
+			var details struct {
+				ID       int
+				Priority file.Priority
+				Due      time.Time
+				Urgency  float64
+				Subtasks []int
+			}
+			if err := json.Unmarshal(buf.Bytes(), &details); err != nil {
+				t.Fatalf("show --format json printed invalid JSON: %v\n%s", err, buf.String())
+			}
+			if details.ID != 1 || details.Priority != file.PriorityHigh || details.Due.IsZero() || details.Urgency <= 0 || !slices.Equal(details.Subtasks, []int{2}) {
+				t.Errorf("show --format json = %+v, want task 1 with its subtask", details)
+			}
+
+			if err := runCommand(cfg, buf, "show", "42"); err == nil {
+				t.Error("expected showing a missing task to fail")
+			}
+		})
+	}
+}
+
+func TestListFormats(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Write report", "+work", "--due", "+2d"},
+				{"add", "Review PR"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+			// add joins words with spaces, tabs only get in through other tools
+			repo, err := file.SelectStorage(cfg.Filepath, storage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			task, err := repo.Get(2)
+			if err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			task.Task = "Review\tPR | now"
+			if _, err := repo.Update(task); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+
+			list := func(args ...string) string {
+				t.Helper()
+				buf.Reset()
+				if err := runCommand(cfg, buf, append([]string{"list", "--sort", "id"}, args...)...); err != nil {
+					t.Fatalf("list %q failed: %v", args, err)
+				}
+				return buf.String()
+			}
+
+			var records []map[string]any
+			if err := json.Unmarshal([]byte(list("--format", "json")), &records); err != nil {
+				t.Fatalf("list --format json printed invalid JSON: %v\n%s", err, buf.String())
+			}
+			if len(records) != 2 || records[0]["description"] != "Write report" || records[0]["due"] == nil ||
+				records[1]["description"] != "Review\tPR | now" || records[1]["due"] != nil {
+				t.Errorf("list --format json = %v", records)
+			}
+
+			lines := strings.Split(strings.TrimSpace(list("--format", "ndjson", "--columns", "id,desc")), "\n")
+			if len(lines) != 2 || lines[0] != `{"id":1,"description":"Write report"}` {
+				t.Errorf("list --format ndjson = %q", lines)
+			}
+
+			want := "id,tags,description\n1,work,Write report\n2,,Review\tPR | now\n"
+			if got := list("--format", "csv", "--columns", "id,tags,desc"); got != want {
+				t.Errorf("list --format csv = %q, want %q", got, want)
+			}
+
+			want = "- id: 1\n  tags:\n    - work\n- id: 2\n  tags: []\n"
+			if got := list("--format", "yaml", "--columns", "id,tags"); got != want {
+				t.Errorf("list --format yaml = %q, want %q", got, want)
+			}
+
+			want = "| ID | Task |\n| --- | --- |\n| 1 | Write report |\n| 2 | Review PR \\| now |\n"
+			if got := list("--format", "markdown", "--columns", "id,desc"); got != want {
+				t.Errorf("list --format markdown = %q, want %q", got, want)
+			}
+
+			// The tab would otherwise add a column
+			got := list("--columns", "desc,id")
+			if !strings.Contains(got, "Task              ID") || !strings.Contains(got, "Review PR | now   2") {
+				t.Errorf("list --columns = %q", got)
+			}
+
+			want = "1: Write report [work]\n2: Review\tPR | now []\n"
+			if got := list("--template", `{{.ID}}: {{.Task}} [{{join .Tags ","}}]`); got != want {
+				t.Errorf("list --template = %q, want %q", got, want)
+			}
+
+			if got := list("--format", "json", "status:done"); got != "[]\n" {
+				t.Errorf("list --format json with no tasks = %q, want []", got)
+			}
+			for _, args := range [][]string{
+				{"list", "--format", "xml"},
+				{"list", "--columns", "id,nope"},
+				{"list", "--template", "{{.ID"},
+				{"list", "--template", "{{.ID}}", "--format", "json"},
+			} {
+				if err := runCommand(cfg, buf, args...); err == nil {
+					t.Errorf("expected %q to fail", args)
+				}
+			}
+		})
+	}
+}
+
+func TestReports(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{
+				Filepath: filepath.Join(dir, "tasks"),
+				Storage:  storage,
+				Reports: map[string]config.Report{
+					"work": {Description: "work by due date", Filter: "project:work", Sort: "due", Columns: []string{"id", "desc", "due"}},
+					"top":  {Limit: 1, Columns: []string{"id,desc"}},
+					// Taken by a command, only runs with tasks report
+					"list": {Filter: "+bug"},
+					"bad":  {Columns: []string{"nope"}},
+				},
+			}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Release v2", "project:work", "--due", "+3d"},
+				{"add", "Fix login", "+bug", "project:work", "--due", "+1d", "--priority", "H"},
+				{"add", "Buy milk"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+
+			run := func(args ...string) string {
+				t.Helper()
+				buf.Reset()
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%q failed: %v", args, err)
+				}
+				return buf.String()
+			}
+
+			got := run("report", "work")
+			if strings.Contains(got, "Buy milk") || strings.Contains(got, "Urg") ||
+				strings.Index(got, "Fix login") > strings.Index(got, "Release v2") {
+				t.Errorf("report work = %q, want work tasks by due date without urgency", got)
+			}
+			if dynamic := run("work"); dynamic != got {
+				t.Errorf("tasks work = %q, want the same as tasks report work %q", dynamic, got)
+			}
+			if got := run("work", "+bug"); strings.Contains(got, "Release v2") || !strings.Contains(got, "Fix login") {
+				t.Errorf("work +bug = %q, want only the bug", got)
+			}
+			if got := run("top"); !strings.Contains(got, "Total tasks: 1") || !strings.Contains(got, "Fix login") {
+				t.Errorf("top = %q, want the most urgent task", got)
+			}
+			if got := run("report", "list"); strings.Contains(got, "Release v2") || !strings.Contains(got, "Fix login") {
+				t.Errorf("report list = %q, want only the bug", got)
+			}
+			if got := run("list"); !strings.Contains(got, "Total tasks: 3") {
+				t.Errorf("list = %q, want the list command to be left alone", got)
+			}
+			if got := run("report"); !strings.Contains(got, "work by due date") || !strings.Contains(got, "limit: 1") {
+				t.Errorf("report = %q, want the reports", got)
+			}
+
+			for _, args := range [][]string{{"report", "nope"}, {"report", "bad"}} {
+				if err := runCommand(cfg, buf, args...); err == nil {
+					t.Errorf("expected %q to fail", args)
+				}
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Fix the login page", "+bug"},
+				{"add", "Release v2"},
+				{"annotate", "2", "after the login fix"},
+				{"add", "Log hours"},
+				{"complete", "1"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+
+			search := func(args ...string) string {
+				t.Helper()
+				buf.Reset()
+				if err := runCommand(cfg, buf, append([]string{"search"}, args...)...); err != nil {
+					t.Fatalf("search %q failed: %v", args, err)
+				}
+				return buf.String()
+			}
+
+			want := "Matching tasks: 2\n1  Fix the login page (done)\n2  Release v2\n   annotation: after the login fix\n"
+			if got := search("LOGIN"); got != want {
+				t.Errorf("search = %q, want %q", got, want)
+			}
+			want = "Matching tasks: 1\n1  \x1b[1;33mFix the login\x1b[0m page (done)\n"
+			if got := search("--color", "always", "--regex", "^fix.*login"); got != want {
+				t.Errorf("search --regex = %q, want %q", got, want)
+			}
+			if got := search("--fuzzy", "lghrs"); !strings.Contains(got, "3  Log hours") || strings.Contains(got, "login") {
+				t.Errorf("search --fuzzy = %q, want task 3", got)
+			}
+			if got := search("--limit", "1", "log"); !strings.Contains(got, "best 1 of 3") || strings.Count(got, "\n") != 2 {
+				t.Errorf("search --limit = %q, want the best result", got)
+			}
+			if got := search("nothing"); got != "No tasks match \"nothing\"\n" {
+				t.Errorf("search = %q, want no tasks", got)
+			}
+
+			for _, args := range [][]string{
+				{"search"},
+				{"search", "--regex", "--fuzzy", "x"},
+				{"search", "--regex", "("},
+				{"search", "--color", "sometimes", "x"},
+			} {
+				if err := runCommand(cfg, buf, args...); err == nil {
+					t.Errorf("expected %q to fail", args)
+				}
+			}
+		})
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Buy milk"},
+				{"add", "Fix login", "+bug"},
+				{"add", "Write tests", "--parent", "2"},
+				{"complete", "1"},
+				{"delete", "2", "--force"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+			list := func() string {
+				t.Helper()
+				buf.Reset()
+				if err := runCommand(cfg, buf, "list", "--all", "--sort", "id", "--format", "csv", "--columns", "id,status,parent"); err != nil {
+					t.Fatalf("list command failed: %v", err)
+				}
+				return buf.String()
+			}
+			deleted := "id,status,parent\n1,done,\n3,pending,\n"
+			if got := list(); got != deleted {
+				t.Fatalf("list = %q, want %q", got, deleted)
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "log", "2"); err != nil {
+				t.Fatalf("log command failed: %v", err)
+			}
+			got := buf.String()
+			for _, want := range []string{"delete 2", "deleted task 2: Fix login", "changed task 3: Write tests (parent)", "add Fix login +bug"} {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in log, got %q", want, got)
+				}
+			}
+			if strings.Contains(got, "Buy milk") {
+				t.Errorf("expected only the changes to task 2 in log, got %q", got)
+			}
+
+			// The subtask moves back under the restored task
+			buf.Reset()
+			if err := runCommand(cfg, buf, "undo"); err != nil {
+				t.Fatalf("undo command failed: %v", err)
+			}
+			if got := list(); got != "id,status,parent\n1,done,\n2,pending,\n3,pending,2\n" {
+				t.Errorf("list after undo = %q", got)
+			}
+			if err := runCommand(cfg, buf, "undo", "2"); err != nil {
+				t.Fatalf("undo command failed: %v", err)
+			}
+			if got := list(); got != "id,status,parent\n1,pending,\n2,pending,\n" {
+				t.Errorf("list after undo 2 = %q", got)
+			}
+
+			// The last undone first, up to the delete
+			if err := runCommand(cfg, buf, "redo", "3"); err != nil {
+				t.Fatalf("redo command failed: %v", err)
+			}
+			if got := list(); got != deleted {
+				t.Errorf("list after redo 3 = %q, want %q", got, deleted)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "log", "--limit", "1"); err != nil {
+				t.Fatalf("log command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "redo of 5") || strings.Count(got, "redo") != 1 {
+				t.Errorf("log --limit 1 = %q, want the last redo", got)
+			}
+
+			for _, args := range [][]string{{"redo"}, {"undo", "0"}, {"undo", "x"}} {
+				if err := runCommand(cfg, buf, args...); err == nil {
+					t.Errorf("expected %q to fail", args)
+				}
+			}
+		})
+	}
+}
+
+func TestTrash(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Buy milk"},
+				{"add", "Plan the trip"},
+				{"add", "Book the hotel", "--parent", "2"},
+				{"delete", "2", "--force", "--subtasks", "delete"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+			list := func(args ...string) string {
+				t.Helper()
+				buf.Reset()
+				if err := runCommand(cfg, buf, append([]string{"list", "--all", "--sort", "id", "--format", "csv", "--columns", "id,parent"}, args...)...); err != nil {
+					t.Fatalf("list command failed: %v", err)
+				}
+				return buf.String()
+			}
+			if got := list(); got != "id,parent\n1,\n" {
+				t.Fatalf("list after delete = %q, want only task 1", got)
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "trash"); err != nil {
+				t.Fatalf("trash command failed: %v", err)
+			}
+			got := buf.String()
+			for _, want := range []string{"Deleted tasks: 2", "Plan the trip", "Book the hotel"} {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in trash, got %q", want, got)
+				}
+			}
+			if strings.Contains(got, "Buy milk") {
+				t.Errorf("expected only deleted tasks in trash, got %q", got)
+			}
+
+			// Deleted tasks cannot be changed until they are restored
+			for _, args := range [][]string{{"modify", "2", "+travel"}, {"annotate", "2", "call the agency"}, {"edit", "3"}} {
+				if err := runCommand(cfg, buf, args...); err == nil || !strings.Contains(err.Error(), "is deleted, tasks restore") {
+					t.Errorf("%q = %v, want an error pointing to restore", args, err)
+				}
+			}
+			if err := runCommand(cfg, buf, "restore", "1"); err == nil {
+				t.Errorf("expected restore of a task that is not deleted to fail")
+			}
+
+			// A subtask of a deleted task moves to the top level
+			buf.Reset()
+			if err := runCommand(cfg, buf, "restore", "3"); err != nil {
+				t.Fatalf("restore command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Moved task 3 to the top level") || !strings.Contains(got, "Restored task 3: Book the hotel") {
+				t.Errorf("restore = %q, want task 3 restored at the top level", got)
+			}
+			if got := list(); got != "id,parent\n1,\n3,\n" {
+				t.Errorf("list after restore = %q", got)
+			}
+
+			// Purging asks first, and only takes what is old enough
+			buf.Reset()
+			if err := runCommand(cfg, buf, "purge", "--older-than", "30d", "--force"); err != nil {
+				t.Fatalf("purge command failed: %v", err)
+			}
+			if got := buf.String(); got != "Nothing to purge\n" {
+				t.Errorf("purge --older-than 30d = %q, want nothing purged", got)
+			}
+			if err := runCommandWithInput(cfg, buf, "n\n", "purge"); err != nil {
+				t.Fatalf("purge command failed: %v", err)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "trash"); err != nil || !strings.Contains(buf.String(), "Plan the trip") {
+				t.Fatalf("trash after a declined purge = %q, %v, want task 2", buf.String(), err)
+			}
+			buf.Reset()
+			if err := runCommandWithInput(cfg, buf, "y\n", "purge"); err != nil {
+				t.Fatalf("purge command failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), "Purged 1 tasks") {
+				t.Errorf("purge = %q, want task 2 purged", buf.String())
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "trash"); err != nil || buf.String() != "The trash is empty\n" {
+				t.Errorf("trash after purge = %q, %v, want it empty", buf.String(), err)
+			}
+			if err := runCommand(cfg, buf, "show", "2"); err == nil {
+				t.Errorf("expected show of a purged task to fail")
+			}
+
+			// Dependencies on deleted tasks only stand in the way of new ones
+			// on them, purging drops them
+			for _, args := range [][]string{
+				{"add", "Pack"},
+				{"modify", "3", "depends:1"},
+				{"delete", "1", "--force"},
+				{"modify", "4", "depends:3"},
+				{"purge", "--force"},
+				{"modify", "4", "depends:3"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%q failed: %v", args, err)
+				}
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "show", "3"); err != nil {
+				t.Fatalf("show command failed: %v", err)
+			}
+			if got := buf.String(); strings.Contains(got, "Depends") {
+				t.Errorf("show after purge = %q, want no dependency on the purged task", got)
+			}
+		})
+	}
+}
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Buy milk"},
+				{"add", "Fix login", "+bug"},
+				{"add", "Call the bank"},
+				{"complete", "1,2"},
+				{"cancel", "3", "--force"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "archive", "--older-than", "30d"); err != nil {
+				t.Fatalf("archive command failed: %v", err)
+			}
+			if got := buf.String(); got != "Nothing to archive\n" {
+				t.Errorf("archive --older-than 30d = %q, want nothing archived", got)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "archive"); err != nil {
+				t.Fatalf("archive command failed: %v", err)
+			}
+			if got := buf.String(); !strings.HasPrefix(got, "Archived 3 tasks to ") {
+				t.Errorf("archive = %q, want 3 tasks archived", got)
+			}
+
+			list := func(args ...string) string {
+				t.Helper()
+				buf.Reset()
+				if err := runCommand(cfg, buf, append([]string{"list", "--sort", "id", "--format", "csv", "--columns", "id,status"}, args...)...); err != nil {
+					t.Fatalf("list command failed: %v", err)
+				}
+				return buf.String()
+			}
+			if got := list("--all"); got != "id,status\n" {
+				t.Errorf("list --all after archive = %q, want no tasks", got)
+			}
+			if got := list("--archived"); got != "id,status\n1,done\n2,done\n3,cancelled\n" {
+				t.Errorf("list --archived = %q", got)
+			}
+			if got := list("--archived", "+bug"); got != "id,status\n2,done\n" {
+				t.Errorf("list --archived +bug = %q", got)
+			}
+
+			// Undoing takes the tasks out of the archive
+			if err := runCommand(cfg, buf, "undo"); err != nil {
+				t.Fatalf("undo command failed: %v", err)
+			}
+			if got := list("status:all"); got != "id,status\n1,done\n2,done\n3,cancelled\n" {
+				t.Errorf("list status:all after undo = %q, want every task back", got)
+			}
+			if got := list("--archived"); got != "id,status\n" {
+				t.Errorf("list --archived after undo = %q, want no tasks", got)
+			}
+			if err := runCommand(cfg, buf, "redo"); err != nil {
+				t.Fatalf("redo command failed: %v", err)
+			}
+			if got := list("--archived"); got != "id,status\n1,done\n2,done\n3,cancelled\n" {
+				t.Errorf("list --archived after redo = %q", got)
+			}
+
+			// New tasks do not take the IDs of archived ones
+			buf.Reset()
+			if err := runCommand(cfg, buf, "add", "Water the plants"); err != nil {
+				t.Fatalf("add command failed: %v", err)
+			}
+			if got := list(); got != "id,status\n4,pending\n" {
+				t.Errorf("list after archive = %q, want task 4", got)
+			}
+		})
+	}
+}
+
+func TestRenumber(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "Plan the trip"},
+				{"add", "Buy milk"},
+				{"add", "Book the hotel", "--parent", "1"},
+				{"add", "Pack", "depends:3"},
+				{"delete", "2", "--force"},
+				{"purge", "--force"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+
+			list := func(args ...string) string {
+				t.Helper()
+				buf.Reset()
+				if err := runCommand(cfg, buf, append([]string{"list", "--sort", "id", "--format", "csv", "--columns", "id,uuid"}, args...)...); err != nil {
+					t.Fatalf("list command failed: %v", err)
+				}
+				return buf.String()
+			}
+			uuids := map[string]string{}
+			for _, line := range strings.Split(strings.TrimSpace(list()), "\n")[1:] {
+				id, uuid, _ := strings.Cut(line, ",")
+				uuids[id] = uuid
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "renumber"); err != nil {
+				t.Fatalf("renumber command failed: %v", err)
+			}
+			if got, want := buf.String(), "Task 3 is now task 2\nTask 4 is now task 3\n"; got != want {
+				t.Errorf("renumber = %q, want %q", got, want)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "renumber"); err != nil {
+				t.Fatalf("renumber command failed: %v", err)
+			}
+			if got := buf.String(); got != "Task IDs are already consecutive\n" {
+				t.Errorf("renumber again = %q", got)
+			}
+
+			want := fmt.Sprintf("id,uuid\n1,%s\n2,%s\n3,%s\n", uuids["1"], uuids["3"], uuids["4"])
+			if got := list(); got != want {
+				t.Errorf("list after renumber = %q, want %q", got, want)
+			}
+			if got := list(uuids["4"][:8]); got != fmt.Sprintf("id,uuid\n3,%s\n", uuids["4"]) {
+				t.Errorf("list <uuid prefix> = %q, want task 3", got)
+			}
+
+			buf.Reset()
+			if err := runCommand(cfg, buf, "show", uuids["3"][:8]); err != nil {
+				t.Fatalf("show command failed: %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Book the hotel") || !strings.Contains(got, uuids["3"]) {
+				t.Errorf("show <uuid prefix> = %q, want the hotel task", got)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "show", uuids["3"][:8]+"0000"); !errors.Is(err, file.ErrNotFound) {
+				t.Errorf("show <unknown uuid> error = %v, want ErrNotFound", err)
+			}
+
+			// New tasks are numbered after the last one
+			buf.Reset()
+			if err := runCommand(cfg, buf, "add", "Water the plants", "--parent", uuids["4"][:8]); err != nil {
+				t.Fatalf("add command failed: %v", err)
+			}
+			buf.Reset()
+			if err := runCommand(cfg, buf, "list", "--sort", "id", "--format", "csv", "--columns", "id,parent"); err != nil {
+				t.Fatalf("list command failed: %v", err)
+			}
+			if got, want := buf.String(), "id,parent\n1,\n2,1\n3,\n4,3\n"; got != want {
+				t.Errorf("list after add = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// Renumbering leaves the IDs of archived tasks alone, so a task archived later
+// never takes the place of an archived one
+func TestRenumberArchived(t *testing.T) {
+	dir := t.TempDir()
+	for _, storage := range []string{"csv", "json", "sqlite"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg := config.Config{Filepath: filepath.Join(dir, "tasks"), Storage: storage}
+			buf := &bytes.Buffer{}
+			for _, args := range [][]string{
+				{"add", "one"},
+				{"add", "two"},
+				{"complete", "1,2"},
+				{"archive"},
+				{"renumber"},
+				{"add", "fresh"},
+			} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+
+			list := func(args ...string) string {
+				t.Helper()
+				buf.Reset()
+				if err := runCommand(cfg, buf, append([]string{"list", "--sort", "id", "--format", "csv", "--columns", "id,description"}, args...)...); err != nil {
+					t.Fatalf("list command failed: %v", err)
+				}
+				return buf.String()
+			}
+			if got := list(); got != "id,description\n3,fresh\n" {
+				t.Errorf("list after renumber and add = %q, want task 3", got)
+			}
+
+			for _, args := range [][]string{{"complete", "3"}, {"archive"}} {
+				if err := runCommand(cfg, buf, args...); err != nil {
+					t.Fatalf("%s command failed: %v", args[0], err)
+				}
+			}
+			if got, want := list("--archived"), "id,description\n1,one\n2,two\n3,fresh\n"; got != want {
+				t.Errorf("list --archived = %q, want %q", got, want)
+			}
+		})
+	}
+}
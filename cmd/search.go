@@ -0,0 +1,155 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/spf13/cobra"
+)
+
+// Bold yellow, reset
+const highlightStart, highlightEnd = "\x1b[1;33m", "\x1b[0m"
+
+// Whether to highlight matches in w for --color
+func useColor(w io.Writer, color string) (bool, error) {
+	switch color {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		// https://no-color.org
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		f, ok := w.(*os.File)
+		if !ok {
+			return false, nil
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("invalid --color %q, use auto, always or never", color)
+	}
+}
+
+// The text of a match on a single line, with the matched parts highlighted
+func highlight(match file.SearchMatch, color bool) string {
+	text := match.Text
+	if color {
+		var b strings.Builder
+		last := 0
+		for _, span := range match.Spans {
+			b.WriteString(text[last:span[0]])
+			b.WriteString(highlightStart + text[span[0]:span[1]] + highlightEnd)
+			last = span[1]
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return cellReplacer.Replace(text)
+}
+
+func writeSearchResults(w io.Writer, results []file.SearchResult, color bool) error {
+	width := 0
+	for _, result := range results {
+		width = max(width, len(strconv.Itoa(result.Task.ID)))
+	}
+	indent := strings.Repeat(" ", width+2)
+
+	for _, result := range results {
+		task := result.Task
+		description := cellReplacer.Replace(task.Task)
+		var others []file.SearchMatch
+		for _, match := range result.Matches {
+			if match.Field == file.SearchFieldDescription {
+				description = highlight(match, color)
+			} else {
+				others = append(others, match)
+			}
+		}
+		if task.Closed() {
+			description += fmt.Sprintf(" (%s)", task.Status)
+		}
+
+		if _, err := fmt.Fprintf(w, "%*d  %s\n", width, task.ID, description); err != nil {
+			return fmt.Errorf("could not write task: %w", err)
+		}
+		for _, match := range others {
+			if _, err := fmt.Fprintf(w, "%s%s: %s\n", indent, match.Field, highlight(match, color)); err != nil {
+				return fmt.Errorf("could not write task: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// searchCmd represents the search command
+func newSearchCmd(storage *file.Repository) *cobra.Command {
+	var regex, fuzzy bool
+	var limit int
+	var color string
+	searchCmd := &cobra.Command{
+		Use:   "search",
+		Short: "search the descriptions, tags and notes of every task",
+		Long: `search every task, completed and cancelled ones included, the best matches first
+tasks search <text> for the tasks containing text, ignoring case
+tasks search --regex '^fix (login|signup)' for a Go regular expression, also
+  ignoring case unless it starts with (?-i)
+tasks search --fuzzy rlsv2 for the tasks with those characters in that order,
+  like "Release v2"
+
+Matches in descriptions rank higher than in tags, which rank higher than in
+notes. Matches are highlighted when writing to a terminal, see --color.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("please provide the text to search for")
+			}
+			if regex && fuzzy {
+				return fmt.Errorf("--regex and --fuzzy cannot be combined")
+			}
+			colored, err := useColor(cmd.OutOrStdout(), color)
+			if err != nil {
+				return err
+			}
+			query := file.SearchQuery{Text: strings.Join(args, " "), Mode: file.SearchSubstring}
+			switch {
+			case regex:
+				query.Mode = file.SearchRegex
+			case fuzzy:
+				query.Mode = file.SearchFuzzy
+			}
+
+			results, err := (*storage).Search(query)
+			if err != nil {
+				return fmt.Errorf("error searching tasks: %w", err)
+			}
+			cmd.SilenceUsage = true
+
+			if len(results) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "No tasks match %q\n", query.Text)
+				return nil
+			}
+			if limit > 0 && len(results) > limit {
+				fmt.Fprintf(cmd.OutOrStdout(), "Showing the best %d of %d tasks, --limit 0 shows them all\n", limit, len(results))
+				results = results[:limit]
+			} else {
+				fmt.Fprintln(cmd.OutOrStdout(), "Matching tasks:", len(results))
+			}
+			return writeSearchResults(cmd.OutOrStdout(), results, colored)
+		},
+	}
+
+	searchCmd.Flags().BoolVarP(&regex, "regex", "r", false, "search with a regular expression")
+	searchCmd.Flags().BoolVar(&fuzzy, "fuzzy", false, "search for the characters in order, with anything in between")
+	searchCmd.Flags().IntVarP(&limit, "limit", "n", 20, "show at most this many tasks, 0 for all")
+	searchCmd.Flags().StringVar(&color, "color", "auto", "highlight matches: auto (when writing to a terminal), always or never")
+	return searchCmd
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/mergestat/timediff"
+	"github.com/spf13/cobra"
+)
+
+const showTimeLayout = "Mon 2006-01-02 15:04"
+
+// An absolute time followed by how long ago (or in how long) it is
+func formatShowTime(t, now time.Time) string {
+	return fmt.Sprintf("%s (%s)", t.Local().Format(showTimeLayout), timediff.TimeDiff(t, timediff.WithStartTime(now)))
+}
+
+// A task along with what is known about it from the other tasks, as printed by
+// tasks show
+type taskDetails struct {
+	file.Task
+	Urgency float64
+	// Pending dependencies, see file.BlockingTasks
+	BlockedBy []int `json:",omitempty"`
+	// IDs of the direct subtasks
+	Subtasks []int `json:",omitempty"`
+}
+
+func newTaskDetails(task file.Task, all []file.Task, now time.Time) taskDetails {
+	details := taskDetails{Task: task, Urgency: task.Urgency(now), BlockedBy: file.BlockingTasks(all, task)}
+	for _, t := range all {
+		if t.Parent == task.ID {
+			details.Subtasks = append(details.Subtasks, t.ID)
+		}
+	}
+	return details
+}
+
+// Print every field of a task, followed by its status history and notes. Empty
+// fields are left out
+func showTask(w io.Writer, d taskDetails, now time.Time) error {
+	tabW := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(tabW, "%s\t%s\n", name, value)
+		}
+	}
+	timeField := func(name string, t time.Time) {
+		if !t.IsZero() {
+			field(name, formatShowTime(t, now))
+		}
+	}
+
+	task := d.Task
+	field("ID", strconv.Itoa(task.ID))
+	field("UUID", task.UUID)
+	field("Task", task.Task)
+	status := string(task.StatusAt(now))
+	if since := task.StatusSince(); !since.IsZero() {
+		status += " since " + formatShowTime(since, now)
+	}
+	field("Status", status)
+	timeField("Wait until", task.WaitUntil)
+	field("Project", task.Project)
+	field("Tags", strings.Join(task.Tags, " "))
+	field("Priority", string(task.Priority))
+	if !task.Due.IsZero() {
+		due := formatShowTime(task.Due, now)
+		if task.Overdue(now) {
+			due += ", overdue"
+		}
+		field("Due", due)
+	}
+	field("Urgency", fmt.Sprintf("%.1f", d.Urgency))
+	if task.IsTemplate() {
+		field("Recur", task.Recur+", template of the recurring task")
+	} else if task.Recur != "" {
+		field("Recur", fmt.Sprintf("%s, instance of template %d", task.Recur, task.RecurParent))
+	}
+	if task.Parent != 0 {
+		field("Parent", strconv.Itoa(task.Parent))
+	}
+	field("Subtasks", joinIDs(d.Subtasks))
+	field("Depends on", joinIDs(task.Depends))
+	field("Blocked by", joinIDs(d.BlockedBy))
+	timeField("Created", task.CreatedAt)
+	timeField("Modified", task.ModifiedAt)
+	timeField("Completed", task.CompletedAt)
+	timeField("Deleted", task.DeletedAt)
+	if len(task.Intervals) > 0 {
+		spent := formatDuration(task.TimeSpent(now))
+		if task.Active() {
+			spent += ", running"
+		}
+		field("Time spent", spent)
+	}
+	if err := tabW.Flush(); err != nil {
+		return err
+	}
+
+	if len(task.StatusChanges) > 0 {
+		fmt.Fprintln(w, "\nStatus history")
+		tabW = tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+		for _, change := range task.StatusChanges {
+			fmt.Fprintf(tabW, "%s\t%s\n", formatShowTime(change.At, now), change.Status)
+		}
+		if err := tabW.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(task.Annotations) > 0 {
+		fmt.Fprintln(w, "\nAnnotations")
+		for _, note := range task.Annotations {
+			fmt.Fprintf(w, "%s\n", formatShowTime(note.At, now))
+			for line := range strings.SplitSeq(note.Text, "\n") {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+	}
+	return nil
+}
+
+// showCmd represents the show command
+func newShowCmd(storage *file.Repository) *cobra.Command {
+	var format string
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "show a task in full",
+		Long: `show every field of a task, along with its status history and notes
+tasks show <task ID>, or the start of its UUID
+tasks show <task ID> --format json for scripts, times are in RFC 3339`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("please provide exactly one task ID to show")
+			}
+			if format != "text" && format != "json" {
+				return fmt.Errorf("invalid format %q, use text or json", format)
+			}
+			taskID, err := parseTaskID(*storage, args[0])
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+
+			task, err := (*storage).Get(taskID)
+			if err != nil {
+				return fmt.Errorf("error showing task: %w", err)
+			}
+			all, err := (*storage).List(file.Filter{All: true})
+			if err != nil {
+				return fmt.Errorf("error showing task: %w", err)
+			}
+			now := time.Now()
+			details := newTaskDetails(task, all, now)
+
+			if format == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(details)
+			}
+			return showTask(cmd.OutOrStdout(), details, now)
+		},
+	}
+
+	showCmd.Flags().StringVar(&format, "format", "text", "output format, text or json")
+	return showCmd
+}
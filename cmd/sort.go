@@ -0,0 +1,84 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+)
+
+// Orders accepted by --sort, prefix with - to reverse them
+var sortKeys = map[string]func(a, b file.Task, now time.Time) int{
+	// Most urgent first
+	"urgency": func(a, b file.Task, now time.Time) int {
+		return cmp.Compare(b.Urgency(now), a.Urgency(now))
+	},
+	"id": func(a, b file.Task, _ time.Time) int {
+		return cmp.Compare(a.ID, b.ID)
+	},
+	"created": func(a, b file.Task, _ time.Time) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	},
+	// Soonest first, tasks without a due date last
+	"due": func(a, b file.Task, _ time.Time) int {
+		switch {
+		case a.Due.IsZero() && b.Due.IsZero():
+			return 0
+		case a.Due.IsZero():
+			return 1
+		case b.Due.IsZero():
+			return -1
+		}
+		return a.Due.Compare(b.Due)
+	},
+	// Highest first
+	"priority": func(a, b file.Task, _ time.Time) int {
+		return cmp.Compare(priorityRank(b.Priority), priorityRank(a.Priority))
+	},
+	"description": func(a, b file.Task, _ time.Time) int {
+		return strings.Compare(strings.ToLower(a.Task), strings.ToLower(b.Task))
+	},
+	// In the order of file.Statuses, started tasks first
+	"status": func(a, b file.Task, now time.Time) int {
+		return cmp.Compare(slices.Index(file.Statuses, a.StatusAt(now)), slices.Index(file.Statuses, b.StatusAt(now)))
+	},
+}
+
+func priorityRank(p file.Priority) int {
+	return strings.Index("LMH", string(p)) + 1 // 0 for no priority
+}
+
+// Sort tasks by a comma separated list of keys (like "due,-priority"), ties are
+// broken by ID
+func sortTasks(tasks []file.Task, keys string, now time.Time) error {
+	var compares []func(a, b file.Task) int
+	for key := range strings.SplitSeq(keys, ",") {
+		key = strings.TrimSpace(key)
+		key, reverse := strings.CutPrefix(key, "-")
+		compare, ok := sortKeys[key]
+		if !ok {
+			return fmt.Errorf("invalid sort key %q, use one of urgency, id, created, due, priority, description or status", key)
+		}
+		if reverse {
+			compares = append(compares, func(a, b file.Task) int { return compare(b, a, now) })
+		} else {
+			compares = append(compares, func(a, b file.Task) int { return compare(a, b, now) })
+		}
+	}
+
+	slices.SortStableFunc(tasks, func(a, b file.Task) int {
+		for _, compare := range compares {
+			if c := compare(a, b); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
+	"github.com/MoXcz/tasks/internal/dates"
+	"github.com/spf13/cobra"
+)
+
+// A command that moves the tasks matching a filter to a status
+type statusChange struct {
+	status file.Status
+	// Verb of the bulk confirmation, like start
+	action string
+	// Whether a task can be moved from the status it is in, the others are
+	// skipped
+	allowed func(task file.Task, now time.Time) bool
+	// Printed for every changed task, like "Started task 1: Write docs"
+	message func(task file.Task) string
+	// Consider closed tasks when the filter does not name IDs or a status
+	all bool
+}
+
+// Open tasks, whatever status they are in now
+func openTask(task file.Task, _ time.Time) bool {
+	return !task.Closed()
+}
+
+func runStatusChange(cmd *cobra.Command, cfg *config.Config, storage file.Repository, args []string, change statusChange, prepare func(task *file.Task)) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please provide the ID of the task to %s or a filter", change.action)
+	}
+
+	tasks, err := selectTasks(storage, args, change.all)
+	if err != nil {
+		return fmt.Errorf("error selecting tasks: %w", err)
+	}
+	cmd.SilenceUsage = true
+	if len(tasks) == 0 {
+		return fmt.Errorf("no tasks match %q", args)
+	}
+
+	force, _ := cmd.Flags().GetBool("force")
+	if !force && len(tasks) > confirmThreshold(cfg) && !confirmBulk(cmd, change.action, tasks) {
+		return nil
+	}
+
+	now := time.Now()
+	for _, task := range tasks {
+		if task.IsTemplate() {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Skipping task %d, it is a recurring task template\n", task.ID)
+			continue
+		}
+		if !change.allowed(task, now) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Skipping task %d, it is %s\n", task.ID, task.StatusAt(now))
+			continue
+		}
+
+		task.SetStatus(change.status, now)
+		if prepare != nil {
+			prepare(&task)
+		}
+		if _, err := storage.Update(task); err != nil {
+			return fmt.Errorf("error updating task: %w", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), change.message(task))
+	}
+	return nil
+}
+
+const statusHelp = `
+The task ID can also be a filter, changing more tasks than confirm_threshold
+(3 by default) at once asks for confirmation first, --force skips it. See tasks
+list --help for filters.`
+
+// waitCmd represents the wait command
+func newWaitCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	var until string
+	waitCmd := &cobra.Command{
+		Use:   "wait",
+		Short: "put a task on hold",
+		Long: `mark a task as waiting, waiting tasks are hidden from tasks list until the
+date given with --until comes, or until they are reopened when there is none
+tasks wait <task ID> --until <date>
+tasks list status:waiting to see them` + statusHelp,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var waitUntil time.Time
+			if until != "" {
+				at, err := dates.Parse(until, time.Now())
+				if err != nil {
+					return fmt.Errorf("invalid wait date: %w", err)
+				}
+				waitUntil = at.UTC()
+			}
+
+			return runStatusChange(cmd, cfg, *storage, args, statusChange{
+				status: file.StatusWaiting,
+				action: "put on hold",
+				allowed: func(task file.Task, now time.Time) bool {
+					// Waiting tasks can be given another date
+					return openTask(task, now)
+				},
+				message: func(task file.Task) string {
+					if task.WaitUntil.IsZero() {
+						return fmt.Sprintf("Task %d is waiting: %s", task.ID, task.Task)
+					}
+					return fmt.Sprintf("Task %d is waiting until %s: %s", task.ID, task.WaitUntil.Local().Format("Mon 2006-01-02 15:04"), task.Task)
+				},
+			}, func(task *file.Task) { task.WaitUntil = waitUntil })
+		},
+	}
+	waitCmd.Flags().StringVar(&until, "until", "", "date the task becomes pending again, like monday or +3d")
+	waitCmd.Flags().BoolP("force", "f", false, "Skip confirmation")
+	return waitCmd
+}
+
+// blockCmd represents the block command
+func newBlockCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	blockCmd := &cobra.Command{
+		Use:   "block",
+		Short: "mark a task as blocked",
+		Long: `mark a task as blocked by something outside of your tasks, use depends:<task ID>
+for tasks blocked by other tasks
+tasks block <task ID>` + statusHelp,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatusChange(cmd, cfg, *storage, args, statusChange{
+				status: file.StatusBlocked,
+				action: "block",
+				allowed: func(task file.Task, now time.Time) bool {
+					return openTask(task, now) && task.StatusAt(now) != file.StatusBlocked
+				},
+				message: func(task file.Task) string { return fmt.Sprintf("Blocked task %d: %s", task.ID, task.Task) },
+			}, nil)
+		},
+	}
+	blockCmd.Flags().BoolP("force", "f", false, "Skip confirmation")
+	return blockCmd
+}
+
+// cancelCmd represents the cancel command
+func newCancelCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	cancelCmd := &cobra.Command{
+		Use:   "cancel",
+		Short: "cancel a task",
+		Long: `mark a task as cancelled, it is closed like a completed task but it was never
+done. Cancelling a recurring task instance does not create the next one
+tasks cancel <task ID>` + statusHelp,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatusChange(cmd, cfg, *storage, args, statusChange{
+				status:  file.StatusCancelled,
+				action:  "cancel",
+				allowed: openTask,
+				message: func(task file.Task) string { return fmt.Sprintf("Cancelled task %d: %s", task.ID, task.Task) },
+			}, nil)
+		},
+	}
+	cancelCmd.Flags().BoolP("force", "f", false, "Skip confirmation")
+	return cancelCmd
+}
+
+// reopenCmd represents the reopen command
+func newReopenCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	reopenCmd := &cobra.Command{
+		Use:   "reopen",
+		Short: "move a task back to pending",
+		Long: `move a completed, cancelled, started, waiting or blocked task back to pending
+tasks reopen <task ID>
+tasks reopen status:waiting to stop waiting on every task` + statusHelp,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatusChange(cmd, cfg, *storage, args, statusChange{
+				status: file.StatusPending,
+				action: "reopen",
+				allowed: func(task file.Task, now time.Time) bool {
+					return task.StatusAt(now) != file.StatusPending
+				},
+				message: func(task file.Task) string { return fmt.Sprintf("Reopened task %d: %s", task.ID, task.Task) },
+				all:     true,
+			}, nil)
+		},
+	}
+	reopenCmd.Flags().BoolP("force", "f", false, "Skip confirmation")
+	return reopenCmd
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/spf13/cobra"
+)
+
+// tagsCmd represents the tags command
+func newTagsCmd(storage *file.Repository) *cobra.Command {
+	var all bool
+	tagsCmd := &cobra.Command{
+		Use:   "tags",
+		Short: "list tags and how many tasks have them",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("this command does not accept any arguments")
+			}
+
+			tasks, err := (*storage).List(file.Filter{All: all})
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
+			}
+
+			counts := map[string]int{}
+			for _, task := range tasks {
+				for _, tag := range task.Tags {
+					counts[tag]++
+				}
+			}
+			return printCounts(cmd.OutOrStdout(), "Tag", counts)
+		},
+	}
+
+	tagsCmd.Flags().BoolVarP(&all, "all", "a", false, "Count completed tasks too")
+	return tagsCmd
+}
+
+// Print name/count pairs, the most used first
+func printCounts(w io.Writer, header string, counts map[string]int) error {
+	if len(counts) == 0 {
+		fmt.Fprintf(w, "No %ss found\n", header)
+		return nil
+	}
+
+	names := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+
+	tabW := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintf(tabW, "%s\t Tasks\n", header)
+	for _, name := range names {
+		fmt.Fprintf(tabW, "%s\t %d\n", name, counts[name])
+	}
+	return tabW.Flush()
+}
@@ -0,0 +1,330 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/config"
+	"github.com/MoXcz/tasks/internal/dates"
+	"github.com/spf13/cobra"
+)
+
+// Tasks time is being tracked on, there is at most one unless the file was
+// edited by hand
+func activeTasks(storage file.Repository) ([]file.Task, error) {
+	return storage.List(file.Filter{All: true, Where: file.Task.Active})
+}
+
+// Stop tracking time on task and move it back to pending
+func stopTask(cmd *cobra.Command, storage file.Repository, task file.Task, now time.Time) error {
+	task.SetStatus(file.StatusPending, now)
+	if _, err := storage.Update(task); err != nil {
+		return fmt.Errorf("error stopping task: %w", err)
+	}
+	last := task.Intervals[len(task.Intervals)-1]
+	fmt.Fprintf(cmd.OutOrStdout(), "Stopped task %d: %s (%s)\n", task.ID, task.Task, formatDuration(last.Duration(now)))
+	return nil
+}
+
+// Hours and minutes, like 1h05m or 25m
+func formatDuration(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
+
+// startCmd represents the start command
+func newStartCmd(cfg *config.Config, storage *file.Repository) *cobra.Command {
+	var autoStop bool
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: "start working on a task",
+		Long: `mark a task as started and track the time spent on it until tasks stop
+tasks start <task ID>
+
+Started tasks are more urgent than pending ones. Time is tracked on one task at
+a time: starting a task while another one is started fails, unless --auto-stop
+is given or auto_stop is set in the config file, which stops the other task
+first. Completing, cancelling or waiting on a started task also stops it.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("please provide the ID of the task to start")
+			}
+			tasks, err := selectTasks(*storage, args, false)
+			if err != nil {
+				return fmt.Errorf("error starting task: %w", err)
+			}
+			cmd.SilenceUsage = true
+			if len(tasks) != 1 {
+				return fmt.Errorf("tasks are started one at a time, %d tasks match %q", len(tasks), args)
+			}
+			task := tasks[0]
+			switch {
+			case task.IsTemplate():
+				return fmt.Errorf("task %d is a recurring task template", task.ID)
+			case task.Closed():
+				return fmt.Errorf("task %d is %s, reopen it first", task.ID, task.Status)
+			case task.Active():
+				return fmt.Errorf("task %d is already started", task.ID)
+			}
+
+			active, err := activeTasks(*storage)
+			if err != nil {
+				return fmt.Errorf("error starting task: %w", err)
+			}
+			now := time.Now()
+			for _, other := range active {
+				if !autoStop && !cfg.AutoStop {
+					return fmt.Errorf("task %d is started, stop it first or use --auto-stop", other.ID)
+				}
+				if err := stopTask(cmd, *storage, other, now); err != nil {
+					return err
+				}
+			}
+
+			task.SetStatus(file.StatusStarted, now)
+			if _, err := (*storage).Update(task); err != nil {
+				return fmt.Errorf("error starting task: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Started task %d: %s\n", task.ID, task.Task)
+			return nil
+		},
+	}
+	startCmd.Flags().BoolVar(&autoStop, "auto-stop", false, "stop the task being worked on, if any")
+	return startCmd
+}
+
+// stopCmd represents the stop command
+func newStopCmd(storage *file.Repository) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "stop working on a task",
+		Long: `stop tracking time on the started task and move it back to pending
+tasks stop to stop whatever task is started
+tasks stop <task ID> to make sure it is that one`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return fmt.Errorf("please provide at most one task ID to stop")
+			}
+			cmd.SilenceUsage = true
+
+			active, err := activeTasks(*storage)
+			if err != nil {
+				return fmt.Errorf("error stopping task: %w", err)
+			}
+			if len(args) == 1 {
+				id, err := parseTaskID(*storage, args[0])
+				if err != nil {
+					return err
+				}
+				active = slices.DeleteFunc(active, func(task file.Task) bool { return task.ID != id })
+				if len(active) == 0 {
+					return fmt.Errorf("task %d is not started", id)
+				}
+			}
+			if len(active) == 0 {
+				return fmt.Errorf("no task is started")
+			}
+
+			now := time.Now()
+			for _, task := range active {
+				if err := stopTask(cmd, *storage, task, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Time tracked on every task between two times, split by local day
+type timesheet struct {
+	from, to time.Time
+	tasks    map[int]file.Task
+	// Local midnight of each day, then task ID
+	days map[time.Time]map[int]time.Duration
+}
+
+func newTimesheet(tasks []file.Task, from, to, now time.Time) timesheet {
+	sheet := timesheet{from: from, to: to, tasks: map[int]file.Task{}, days: map[time.Time]map[int]time.Duration{}}
+	for _, task := range tasks {
+		for _, interval := range task.Intervals {
+			start := interval.Start.Local()
+			end := now
+			if !interval.End.IsZero() {
+				end = interval.End.Local()
+			}
+			start, end = later(start, from), earlier(end, to)
+
+			// Intervals that go past midnight count for both days
+			for start.Before(end) {
+				day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+				next := earlier(day.AddDate(0, 0, 1), end)
+				if sheet.days[day] == nil {
+					sheet.days[day] = map[int]time.Duration{}
+				}
+				sheet.days[day][task.ID] += next.Sub(start)
+				sheet.tasks[task.ID] = task
+				start = next
+			}
+		}
+	}
+	return sheet
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func hours(d time.Duration) string {
+	return strconv.FormatFloat(d.Hours(), 'f', 2, 64)
+}
+
+// One row per day and task, for spreadsheets
+func (s timesheet) writeCSV(w io.Writer) error {
+	csvW := csv.NewWriter(w)
+	if err := csvW.Write([]string{"Date", "ID", "Task", "Project", "Tags", "Hours"}); err != nil {
+		return fmt.Errorf("could not write header: %w", err)
+	}
+	for _, day := range slices.SortedFunc(maps.Keys(s.days), time.Time.Compare) {
+		for _, id := range slices.Sorted(maps.Keys(s.days[day])) {
+			task := s.tasks[id]
+			row := []string{day.Format(time.DateOnly), strconv.Itoa(id), task.Task, task.Project, strings.Join(task.Tags, " "), hours(s.days[day][id])}
+			if err := csvW.Write(row); err != nil {
+				return fmt.Errorf("could not write row: %w", err)
+			}
+		}
+	}
+	csvW.Flush()
+	return csvW.Error()
+}
+
+// Totals per task, tag and day
+func (s timesheet) writeTable(w io.Writer) error {
+	perTask := map[int]time.Duration{}
+	perTag := map[string]time.Duration{}
+	perDay := map[time.Time]time.Duration{}
+	var total time.Duration
+	for day, tasks := range s.days {
+		for id, spent := range tasks {
+			perTask[id] += spent
+			perDay[day] += spent
+			total += spent
+			for _, tag := range s.tasks[id].Tags {
+				perTag[tag] += spent
+			}
+			if len(s.tasks[id].Tags) == 0 {
+				perTag["(no tag)"] += spent
+			}
+		}
+	}
+
+	tabW := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintf(tabW, "Time tracked from %s to %s: %s hours\n", s.from.Format(completedLayout), s.to.Format(completedLayout), hours(total))
+
+	fmt.Fprintln(tabW, "\nID\t Task\t Hours")
+	ids := slices.SortedFunc(maps.Keys(perTask), func(a, b int) int {
+		return cmp.Or(cmp.Compare(perTask[b], perTask[a]), cmp.Compare(a, b))
+	})
+	for _, id := range ids {
+		fmt.Fprintf(tabW, "%d\t %s\t %s\n", id, s.tasks[id].Task, hours(perTask[id]))
+	}
+
+	// A task with several tags counts for each of them
+	fmt.Fprintln(tabW, "\nTag\t Hours")
+	for _, tag := range slices.Sorted(maps.Keys(perTag)) {
+		fmt.Fprintf(tabW, "%s\t %s\n", tag, hours(perTag[tag]))
+	}
+
+	fmt.Fprintln(tabW, "\nDay\t Hours")
+	for _, day := range slices.SortedFunc(maps.Keys(perDay), time.Time.Compare) {
+		fmt.Fprintf(tabW, "%s\t %s\n", day.Format("Mon 2006-01-02"), hours(perDay[day]))
+	}
+	return tabW.Flush()
+}
+
+// timesheetCmd represents the timesheet command
+func newTimesheetCmd(storage *file.Repository) *cobra.Command {
+	var week bool
+	var since, until, format string
+	timesheetCmd := &cobra.Command{
+		Use:   "timesheet",
+		Short: "summarize the time tracked on tasks",
+		Long: `summarize the time tracked with tasks start and stop per task, tag and day
+tasks timesheet --week for the current week, starting on monday (the default)
+tasks timesheet --since 2025-06-01 --until 2025-06-30 for any other period
+tasks timesheet <filter> to only count the tasks matching a filter
+tasks timesheet --format csv for one row per day and task, for spreadsheets`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "csv" {
+				return fmt.Errorf("invalid format %q, use table or csv", format)
+			}
+			if week && (since != "" || until != "") {
+				return fmt.Errorf("--week cannot be combined with --since or --until")
+			}
+
+			now := time.Now()
+			from, to := now, now
+			if since == "" {
+				from, _ = dates.Since("mon", now)
+			} else {
+				var err error
+				if from, err = dates.Since(since, now); err != nil {
+					return fmt.Errorf("invalid --since: %w", err)
+				}
+			}
+			if until != "" {
+				var err error
+				if to, err = dates.Parse(until, now); err != nil {
+					return fmt.Errorf("invalid --until: %w", err)
+				}
+			}
+
+			tasks, err := selectTasks(*storage, args, true)
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
+			}
+			cmd.SilenceUsage = true
+
+			sheet := newTimesheet(tasks, from, to, now)
+			if format == "csv" {
+				return sheet.writeCSV(cmd.OutOrStdout())
+			}
+			if len(sheet.days) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No time tracked since", from.Format(completedLayout))
+				return nil
+			}
+			return sheet.writeTable(cmd.OutOrStdout())
+		},
+	}
+
+	timesheetCmd.Flags().BoolVar(&week, "week", false, "summarize the current week, starting on monday")
+	timesheetCmd.Flags().StringVar(&since, "since", "", "start of the period, like 2w or 2025-06-01, monday by default")
+	timesheetCmd.Flags().StringVar(&until, "until", "", "end of the period, now by default")
+	timesheetCmd.Flags().StringVar(&format, "format", "table", "output format, table or csv")
+	return timesheetCmd
+}
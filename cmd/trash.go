@@ -0,0 +1,190 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/dates"
+	"github.com/MoXcz/tasks/internal/filter"
+	"github.com/spf13/cobra"
+)
+
+// Returned by the commands that cannot change a deleted task
+func deletedError(task file.Task) error {
+	return fmt.Errorf("task %d is deleted, tasks restore %d brings it back", task.ID, task.ID)
+}
+
+// Deleted tasks matching the filter expression in args, every one of them
+// without args. A filter with a single ID fails when the task is not deleted
+func selectDeleted(storage file.Repository, args []string) ([]file.Task, error) {
+	f, err := filter.Parse(args, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := storage.List(file.Filter{Deleted: true, Where: f.Match})
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := f.ID(); ok && len(tasks) == 0 {
+		if _, err := storage.Get(id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("task %d is not deleted", id)
+	}
+	return tasks, nil
+}
+
+// Columns of tasks trash
+const trashColumns = "id,description,status,deleted"
+
+// trashCmd represents the trash command
+func newTrashCmd(storage *file.Repository) *cobra.Command {
+	trashCmd := &cobra.Command{
+		Use:   "trash",
+		Short: "list the deleted tasks",
+		Long: `list the tasks moved to the trash by tasks delete, the most recently deleted first
+tasks trash to see every deleted task
+tasks trash <filter> to only see some of them, like tasks trash project:old
+
+Deleted tasks stay in the trash until tasks purge removes them, tasks restore
+brings them back.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tasks, err := selectDeleted(*storage, args)
+			if err != nil {
+				return fmt.Errorf("error listing deleted tasks: %w", err)
+			}
+			cmd.SilenceUsage = true
+
+			if len(tasks) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "The trash is empty")
+				return nil
+			}
+			slices.SortStableFunc(tasks, func(a, b file.Task) int { return b.DeletedAt.Compare(a.DeletedAt) })
+			columns, err := parseColumns(trashColumns)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Deleted tasks:", len(tasks))
+			return writeTable(cmd.OutOrStdout(), tasks, printContext{now: time.Now()}, columns)
+		},
+	}
+	return trashCmd
+}
+
+// restoreCmd represents the restore command
+func newRestoreCmd(storage *file.Repository) *cobra.Command {
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "bring deleted tasks back",
+		Long: `bring tasks back from the trash, as they were when deleted
+tasks restore <task ID> to restore a deleted task
+tasks restore <filter> to restore every deleted task matching a filter, like
+  tasks restore 1,3,5-9
+  tasks restore project:old
+
+A restored subtask whose parent is still deleted moves to the top level.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("please provide the ID of the task to restore or a filter")
+			}
+			tasks, err := selectDeleted(*storage, args)
+			if err != nil {
+				return fmt.Errorf("error restoring task: %w", err)
+			}
+			cmd.SilenceUsage = true
+			if len(tasks) == 0 {
+				fmt.Fprintln(cmd.OutOrStderr(), "No matching tasks")
+				return nil
+			}
+
+			restoring := map[int]bool{}
+			for _, task := range tasks {
+				restoring[task.ID] = true
+			}
+			for _, task := range tasks {
+				task.DeletedAt = time.Time{}
+				if task.Parent != 0 && !restoring[task.Parent] {
+					parent, err := (*storage).Get(task.Parent)
+					if err != nil && !errors.Is(err, file.ErrNotFound) {
+						return fmt.Errorf("error restoring task: %w", err)
+					}
+					if err != nil || parent.Deleted() {
+						fmt.Fprintf(cmd.OutOrStdout(), "Moved task %d to the top level, its parent %d is deleted\n", task.ID, task.Parent)
+						task.Parent = 0
+					}
+				}
+				if _, err := (*storage).Update(task); err != nil {
+					return fmt.Errorf("error restoring task: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Restored task %d: %s\n", task.ID, task.Task)
+			}
+			return nil
+		},
+	}
+	return restoreCmd
+}
+
+// purgeCmd represents the purge command
+func newPurgeCmd(storage *file.Repository) *cobra.Command {
+	var olderThan string
+	var force bool
+	purgeCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "remove deleted tasks for good",
+		Long: `remove tasks from the trash for good, they cannot be restored after that
+tasks purge to empty the trash
+tasks purge --older-than 30d to only remove the tasks deleted more than 30 days ago
+tasks purge <filter> to only remove some of them, like tasks purge project:old
+
+Subtasks of purged tasks move to the top level and dependencies on them are
+dropped. Purging always asks for confirmation first, --force skips it. A purge
+can still be reverted with tasks undo.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			var before time.Time
+			if olderThan != "" {
+				var err error
+				if before, err = dates.Since(olderThan, now); err != nil {
+					return fmt.Errorf("invalid --older-than: %w", err)
+				}
+			}
+			tasks, err := selectDeleted(*storage, args)
+			if err != nil {
+				return fmt.Errorf("error purging tasks: %w", err)
+			}
+			cmd.SilenceUsage = true
+
+			if !before.IsZero() {
+				tasks = slices.DeleteFunc(tasks, func(task file.Task) bool { return !task.DeletedAt.Before(before) })
+			}
+			if len(tasks) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "Nothing to purge")
+				return nil
+			}
+			if !force && !confirmBulk(cmd, "permanently delete", tasks) {
+				return nil
+			}
+
+			ids := make([]int, len(tasks))
+			for i, task := range tasks {
+				ids[i] = task.ID
+			}
+			if err := file.Purge(*storage, ids); err != nil {
+				return fmt.Errorf("error purging tasks: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Purged %d tasks\n", len(tasks))
+			return nil
+		},
+	}
+
+	purgeCmd.Flags().StringVar(&olderThan, "older-than", "", "only the tasks deleted before this date or this long ago, like 30d")
+	purgeCmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation")
+	return purgeCmd
+}
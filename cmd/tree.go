@@ -0,0 +1,54 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package cmd
+
+import (
+	"strings"
+
+	"github.com/MoXcz/tasks/file"
+)
+
+// Order sorted tasks so subtasks come right after their parent, keeping the
+// order among siblings, and indent their descriptions by their depth. Tasks
+// whose parent is not in the list are shown at the top level
+func treeOrder(tasks []file.Task) []file.Task {
+	listed := map[int]bool{}
+	for _, task := range tasks {
+		listed[task.ID] = true
+	}
+	children := map[int][]file.Task{}
+	var roots []file.Task
+	for _, task := range tasks {
+		if task.Parent != 0 && listed[task.Parent] && task.Parent != task.ID {
+			children[task.Parent] = append(children[task.Parent], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+
+	ordered := make([]file.Task, 0, len(tasks))
+	seen := map[int]bool{}
+	var walk func(task file.Task, depth int)
+	walk = func(task file.Task, depth int) {
+		if seen[task.ID] {
+			return
+		}
+		seen[task.ID] = true
+		if depth > 0 {
+			task.Task = strings.Repeat("  ", depth-1) + "└ " + task.Task
+		}
+		ordered = append(ordered, task)
+		for _, child := range children[task.ID] {
+			walk(child, depth+1)
+		}
+	}
+	for _, task := range roots {
+		walk(task, 0)
+	}
+	// Only tasks in a parent loop are left, which writes never create
+	for _, task := range tasks {
+		walk(task, 0)
+	}
+	return ordered
+}
@@ -0,0 +1,54 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Returned when annotating a task with an empty note
+var ErrEmptyAnnotation = errors.New("annotation is empty")
+
+// A note added to a task, it can span several lines
+type Annotation struct {
+	At   time.Time
+	Text string
+}
+
+// Append a note to the task, leading and trailing blank space is dropped
+func (t *Task) Annotate(text string, at time.Time) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ErrEmptyAnnotation
+	}
+	t.Annotations = append(t.Annotations, Annotation{At: at.UTC(), Text: text})
+	return nil
+}
+
+// Annotations can hold any text, newlines and commas included, so unlike tags
+// they are stored as a JSON array, which keeps them on a single line. Empty
+// when there are none
+func formatAnnotations(annotations []Annotation) string {
+	if len(annotations) == 0 {
+		return ""
+	}
+	// Times and strings always marshal
+	data, _ := json.Marshal(annotations)
+	return string(data)
+}
+
+func parseAnnotations(value string) ([]Annotation, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var annotations []Annotation
+	if err := json.Unmarshal([]byte(value), &annotations); err != nil {
+		return nil, fmt.Errorf("error decoding annotations: %w", err)
+	}
+	return annotations, nil
+}
@@ -0,0 +1,51 @@
+package file
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTask_Annotate(t *testing.T) {
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			repo, err := SelectStorage(filepath.Join(dir, "tasks"), storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			task, err := repo.Create(Task{Task: "renew passport"})
+			if err != nil {
+				t.Fatalf("Create() failed: %v", err)
+			}
+
+			notes := []string{
+				"called the embassy",
+				"needs:\n- a photo, \"biometric\"\r\n- the old passport\n\tand a form",
+			}
+			for i, note := range notes {
+				if err := task.Annotate("  "+note+"\n", now.Add(time.Duration(i)*time.Hour)); err != nil {
+					t.Fatalf("Annotate() failed: %v", err)
+				}
+			}
+			if err := task.Annotate(" \n ", now); !errors.Is(err, ErrEmptyAnnotation) {
+				t.Errorf("Annotate() of a blank note = %v, want ErrEmptyAnnotation", err)
+			}
+			if _, err := repo.Update(task); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+
+			got, err := repo.Get(task.ID)
+			if err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			want := []Annotation{{now, notes[0]}, {now.Add(time.Hour), notes[1]}}
+			if !slices.Equal(got.Annotations, want) {
+				t.Errorf("Annotations = %q, want %q", got.Annotations, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Closed tasks moved out of the tasks of path on day, for SelectStorage. They
+// are kept in the same format, like tasks.archive-2025-06-18.csv next to
+// tasks.csv
+func ArchivePath(path string, day time.Time) string {
+	return path + ".archive-" + day.Format(time.DateOnly)
+}
+
+// Every archive of the tasks of path, the oldest first
+func ArchivePaths(path, storageType string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error looking for archives: %w", err)
+	}
+	prefix := filepath.Base(path) + ".archive-"
+	var paths []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), "."+storageType)
+		if ok && strings.HasPrefix(name, prefix) && !entry.IsDir() {
+			paths = append(paths, filepath.Join(filepath.Dir(path), name))
+		}
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// The IDs of the tasks in every archive of the tasks of path, which Renumber
+// must not hand out again
+func ArchivedIDs(path, storageType string) ([]int, error) {
+	paths, err := ArchivePaths(path, storageType)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for _, p := range paths {
+		archive, err := SelectStorage(p, storageType)
+		if err != nil {
+			return nil, err
+		}
+		tasks, err := archive.List(Filter{All: true})
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", p, err)
+		}
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+	}
+	return ids, nil
+}
+
+// When a closed task was done or cancelled
+func closedAt(task Task) time.Time {
+	return cmp.Or(task.StatusSince(), task.CompletedAt, task.ModifiedAt)
+}
+
+// Tasks that Archive moves: closed before the given time (any time when it is
+// zero) and not deleted. A task is only archived along with all of its
+// subtasks, so none is left without its parent
+func archivable(tasks []Task, before time.Time) map[int]bool {
+	selected := map[int]bool{}
+	for _, task := range tasks {
+		if task.Closed() && !task.Deleted() && (before.IsZero() || closedAt(task).Before(before)) {
+			selected[task.ID] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, task := range tasks {
+			if task.Parent != 0 && selected[task.Parent] && !selected[task.ID] {
+				delete(selected, task.Parent)
+				changed = true
+			}
+		}
+	}
+	return selected
+}
+
+// Move the closed tasks of repo to the archive at path (see ArchivePath),
+// keeping their IDs, and return them. Only the tasks closed before the given
+// time are moved unless it is zero. The IDs of archived tasks are never given
+// to new tasks of repo. When repo is recorded by a Journal, undoing the change
+// takes the tasks out of the archive again
+func Archive(repo Repository, path, storageType string, before time.Time) ([]Task, error) {
+	archive, err := SelectStorage(path, storageType)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := repo.(*journaledRepository); ok {
+		r.archive = path + "." + storageType
+		defer func() { r.archive = "" }()
+	}
+
+	var moved []Task
+	err = repo.Transact(func(snap *Snapshot) error {
+		// Files written before LastID existed would otherwise hand out the IDs
+		// of the last tasks again
+		for _, task := range snap.Tasks {
+			snap.LastID = max(snap.LastID, task.ID)
+		}
+		selected := archivable(snap.Tasks, before)
+		if len(selected) == 0 {
+			return nil
+		}
+		var kept []Task
+		for _, task := range snap.Tasks {
+			if selected[task.ID] {
+				moved = append(moved, task)
+			} else {
+				kept = append(kept, task)
+			}
+		}
+
+		// Archived first, so a failure never loses tasks. Archiving again on
+		// the same day adds to the same archive
+		err := archive.Transact(func(archived *Snapshot) error {
+			archived.LastID = max(archived.LastID, snap.LastID)
+			return addArchived(archived, moved)
+		})
+		if err != nil {
+			return fmt.Errorf("error writing archive: %w", err)
+		}
+		snap.Tasks = kept
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// Add tasks to an archive in place of their earlier copies, told apart by
+// their UUID. Fails when the archive has another task with the ID of one of
+// them
+func addArchived(archived *Snapshot, tasks []Task) error {
+	uuids := map[string]bool{}
+	for _, task := range tasks {
+		uuids[task.UUID] = true
+	}
+	archived.Tasks = slices.DeleteFunc(archived.Tasks, func(task Task) bool { return uuids[task.UUID] })
+	for _, task := range tasks {
+		if slices.ContainsFunc(archived.Tasks, func(t Task) bool { return t.ID == task.ID }) {
+			return fmt.Errorf("the archive has another task %d", task.ID)
+		}
+	}
+	archived.Tasks = append(archived.Tasks, tasks...)
+	slices.SortFunc(archived.Tasks, func(a, b Task) int { return a.ID - b.ID })
+	return nil
+}
+
+// The archive at file, like tasks.archive-2025-06-18.csv, see
+// JournalEntry.Archive
+func openArchive(file string) (Repository, error) {
+	ext := filepath.Ext(file)
+	return SelectStorage(strings.TrimSuffix(file, ext), strings.TrimPrefix(ext, "."))
+}
+
+// Put tasks back into the archive at file, when redoing an Archive
+func rearchive(file string, tasks []Task) error {
+	archive, err := openArchive(file)
+	if err != nil {
+		return err
+	}
+	return archive.Transact(func(archived *Snapshot) error {
+		return addArchived(archived, tasks)
+	})
+}
+
+// Take tasks out of the archive at file, when undoing an Archive. They are
+// told apart by their UUID, those that are no longer there are left out
+func unarchive(file string, tasks []Task) error {
+	archive, err := openArchive(file)
+	if err != nil {
+		return err
+	}
+	return archive.Transact(func(archived *Snapshot) error {
+		archived.Tasks = slices.DeleteFunc(archived.Tasks, func(task Task) bool {
+			return slices.ContainsFunc(tasks, func(t Task) bool { return t.UUID == task.UUID })
+		})
+		return nil
+	})
+}
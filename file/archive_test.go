@@ -0,0 +1,173 @@
+package file
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tasks")
+			repo, err := SelectStorage(path, storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			closed := func(status Status, at time.Time) Task {
+				task := Task{}
+				task.SetStatus(status, at)
+				return task
+			}
+			deleted := closed(StatusDone, now.AddDate(0, -2, 0))
+			deleted.DeletedAt = now
+			for _, task := range []Task{
+				closed(StatusDone, now.AddDate(0, -2, 0)),
+				{},
+				closed(StatusDone, now.AddDate(0, 0, -1)),
+				// Kept for its pending subtask
+				closed(StatusCancelled, now.AddDate(0, -2, 0)),
+				{Parent: 4},
+				deleted,
+				closed(StatusDone, now.AddDate(0, -2, 0)),
+			} {
+				task.Task = "task"
+				if _, err := repo.Create(task); err != nil {
+					t.Fatalf("Create() failed: %v", err)
+				}
+			}
+
+			archivePath := ArchivePath(path, now)
+			archive, err := SelectStorage(archivePath, storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := func(tasks []Task) []int {
+				var ids []int
+				for _, task := range tasks {
+					ids = append(ids, task.ID)
+				}
+				return ids
+			}
+
+			moved, err := Archive(repo, archivePath, storageType, now.AddDate(0, 0, -30))
+			if err != nil {
+				t.Fatalf("Archive() failed: %v", err)
+			}
+			if got := ids(moved); !slices.Equal(got, []int{1, 7}) {
+				t.Errorf("Archive() moved %v, want [1 7]", got)
+			}
+			// Archiving again the same day adds to the same archive
+			if moved, err = Archive(repo, archivePath, storageType, time.Time{}); err != nil || !slices.Equal(ids(moved), []int{3}) {
+				t.Errorf("Archive() = %v, %v, want [3]", ids(moved), err)
+			}
+			if moved, err = Archive(repo, archivePath, storageType, time.Time{}); err != nil || moved != nil {
+				t.Errorf("Archive() with nothing left = %v, %v, want nothing", ids(moved), err)
+			}
+
+			archived, err := archive.List(Filter{All: true})
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			if got := ids(archived); !slices.Equal(got, []int{1, 3, 7}) {
+				t.Errorf("archive has %v, want [1 3 7]", got)
+			}
+			left, err := repo.Export()
+			if err != nil {
+				t.Fatalf("Export() failed: %v", err)
+			}
+			if got := ids(left.Tasks); !slices.Equal(got, []int{2, 4, 5, 6}) {
+				t.Errorf("tasks left = %v, want [2 4 5 6]", got)
+			}
+
+			// The IDs of archived tasks are not handed out again
+			created, err := repo.Create(Task{Task: "new"})
+			if err != nil || created.ID != 8 {
+				t.Errorf("Create() after archiving = %+v, %v, want ID 8", created, err)
+			}
+
+			paths, err := ArchivePaths(path, storageType)
+			if err != nil || !slices.Equal(paths, []string{ArchivePath(path, now)}) {
+				t.Errorf("ArchivePaths() = %q, %v, want the archive", paths, err)
+			}
+
+			// Archived tasks are told apart by their UUID, another task with the
+			// ID of an archived one is not archived over it
+			other := closed(StatusDone, now)
+			other.Task, other.ID, other.UUID, other.CreatedAt = "other", 8, newUUID(), now
+			if err := archive.Replace(Snapshot{Tasks: append(archived, other)}); err != nil {
+				t.Fatalf("Replace() failed: %v", err)
+			}
+			created.SetStatus(StatusDone, now)
+			if _, err := repo.Update(created); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+			if _, err := Archive(repo, archivePath, storageType, time.Time{}); err == nil {
+				t.Error("Archive() over another task with the same ID succeeded, want an error")
+			}
+			if _, err := repo.Get(8); err != nil {
+				t.Errorf("Get() after a failed Archive() = %v, want the task kept", err)
+			}
+		})
+	}
+}
+
+func TestArchive_Undo(t *testing.T) {
+	now := time.Now()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tasks")
+			store, err := SelectStorage(path, storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			journal := NewJournal(JournalPath(path, storageType))
+			done := Task{Task: "buy milk"}
+			done.SetStatus(StatusDone, now)
+			for _, task := range []Task{done, {Task: "call the bank"}} {
+				if _, err := store.Create(task); err != nil {
+					t.Fatalf("Create() failed: %v", err)
+				}
+			}
+
+			archivePath := ArchivePath(path, now)
+			archive, err := SelectStorage(archivePath, storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Archive(journal.Record(store, "archive"), archivePath, storageType, time.Time{}); err != nil {
+				t.Fatalf("Archive() failed: %v", err)
+			}
+			where := func() (kept, archived int) {
+				t.Helper()
+				tasks, err := store.List(Filter{All: true})
+				if err != nil {
+					t.Fatalf("List() failed: %v", err)
+				}
+				moved, err := archive.List(Filter{All: true})
+				if err != nil {
+					t.Fatalf("List() failed: %v", err)
+				}
+				return len(tasks), len(moved)
+			}
+			if kept, archived := where(); kept != 1 || archived != 1 {
+				t.Errorf("after Archive() %d tasks are kept and %d archived, want 1 and 1", kept, archived)
+			}
+
+			if _, err := journal.Undo(store, 1, false); err != nil {
+				t.Fatalf("Undo() failed: %v", err)
+			}
+			if kept, archived := where(); kept != 2 || archived != 0 {
+				t.Errorf("after Undo() %d tasks are kept and %d archived, want 2 and 0", kept, archived)
+			}
+			if _, err := journal.Redo(store, 1, false); err != nil {
+				t.Fatalf("Redo() failed: %v", err)
+			}
+			if kept, archived := where(); kept != 1 || archived != 1 {
+				t.Errorf("after Redo() %d tasks are kept and %d archived, want 1 and 1", kept, archived)
+			}
+		})
+	}
+}
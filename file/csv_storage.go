@@ -8,242 +8,250 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
-	"os"
-	"slices"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/spf13/viper"
 )
 
 type CSVStorage struct {
-	filepath string
+	fileStore
 }
 
 func NewCSVStorage(filepath string) *CSVStorage {
 	return &CSVStorage{
-		filepath: filepath,
+		fileStore{
+			filepath: filepath,
+			read:     readTasksCSV,
+			write:    writeTasksCSV,
+		},
 	}
 }
 
-func (s *CSVStorage) AddTask(task string) error {
-	file, err := LoadFile(s.filepath)
-	if err != nil {
-		return fmt.Errorf("error loading file: %w", err)
-	}
-	defer CloseFile(file)
-
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("error getting file info: %w", err)
-	}
+// File metadata is kept in "#key=value" lines before the header
+const csvLastIDKey = "#last_id="
 
-	csvWriter := csv.NewWriter(file)
-	// Write the header if the file is empty
-	if fileInfo.Size() == 0 {
-		if err := csvWriter.Write([]string{"ID", "Task", "CreatedAt", "IsComplete"}); err != nil {
-			return fmt.Errorf("error writing header to file: %w", err)
+func readTasksCSV(r io.Reader) (Snapshot, error) {
+	var snap Snapshot
+	bufR := bufio.NewReader(r)
+	for {
+		peek, err := bufR.Peek(1)
+		if err != nil || peek[0] != '#' {
+			break
 		}
-	}
-
-	csvReader := csv.NewReader(file)
-	// Read the existing records to find the next ID
-	records, err := csvReader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("error reading existing records: %w", err)
-	}
-
-	lastID := 0 // if len(records) < 0, lastID will be 1 (+1 below)
-	if len(records) > 0 {
-		lastRecord := records[len(records)-1]
-		if lastRecord[0] != "ID" { // Skip header
-			lastID, err = strconv.Atoi(lastRecord[0])
+		line, err := bufR.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return Snapshot{}, fmt.Errorf("error reading CSV file: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if value, ok := strings.CutPrefix(line, csvLastIDKey); ok {
+			snap.LastID, err = strconv.Atoi(value)
 			if err != nil {
-				return fmt.Errorf("error converting last ID to integer: %w", err)
+				return Snapshot{}, fmt.Errorf("error converting last ID to integer: %w", err)
 			}
 		}
 	}
 
-	record := []string{strconv.Itoa(lastID + 1), task, time.Now().UTC().Format(time.RFC1123), "false"}
-	if err := csvWriter.Write(record); err != nil {
-		return fmt.Errorf("error writing task to file: %w", err)
-	}
+	csvReader := csv.NewReader(bufR)
 
-	csvWriter.Flush()
-	if err := csvWriter.Error(); err != nil {
-		return fmt.Errorf("error flushing writer: %w", err)
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("error reading CSV file: %w", err)
 	}
 
-	return nil
-}
-
-func (s *CSVStorage) ListTasks(w io.Writer) error {
-	tasks, err := readTasksCSV(s.filepath)
-	if err != nil {
-		return err // Error already formatted in readTasksCSV
+	// TODO: check whether if longer returning an error broke something
+	if len(records) == 0 {
+		return snap, nil
 	}
 
-	if len(tasks) == 0 {
-		fmt.Println("No tasks found in the file: header only")
-		return nil
+	// Columns are looked up by name, files written before a column was added
+	// simply lack it
+	columns := map[string]int{}
+	for i, name := range csvHeader {
+		columns[name] = i
 	}
 
-	fmt.Fprintln(w, "Total tasks:", len(tasks))
-	printTasks(w, tasks)
+	for _, record := range records {
+		if record[0] == "ID" {
+			clear(columns)
+			for i, name := range record {
+				columns[name] = i
+			}
+			continue // Skip the header row
+		}
+		task, err := taskFromCSV(record, columns)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		snap.Tasks = append(snap.Tasks, task)
+	}
 
-	return nil
+	return snap, nil
 }
 
-func (s *CSVStorage) CompleteTask(w io.Writer, id int) error {
-	if id <= 0 {
-		return fmt.Errorf("task ID must be greater than 0, got %d", id)
+func writeTasksCSV(w io.Writer, snap Snapshot) error {
+	if _, err := fmt.Fprintf(w, "%s%d\n", csvLastIDKey, snap.LastID); err != nil {
+		return fmt.Errorf("error writing metadata to file: %w", err)
 	}
 
-	found := false
-	tasks, err := readTasksCSV(s.filepath)
-	if err != nil {
-		return err
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(csvHeader); err != nil {
+		return fmt.Errorf("error writing header to file: %w", err)
 	}
 
-	// I'd think it's necessary to iterate through the list of tasks to make sure
-	// both that the task exists and that it's not already completed
-	for i, task := range tasks {
-		if task.ID == id && task.IsComplete {
-			return fmt.Errorf("task with ID %d is already completed", id)
-		}
-		if task.ID == id {
-			fmt.Fprintln(w, "Completing task:", task.Task)
-			tasks[i].IsComplete = true
-			found = true
-			break
+	for _, task := range snap.Tasks {
+		if err := csvWriter.Write(taskToCSV(task)); err != nil {
+			return fmt.Errorf("error writing task to file: %w", err)
 		}
 	}
 
-	if !found {
-		return fmt.Errorf("task with ID %d not found", id)
-	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
 
-	return writeTasksCSV(s.filepath, tasks)
+// Columns written by writeTasksCSV, new ones are only ever appended
+var csvHeader = []string{"ID", "Task", "CreatedAt", "IsComplete", "Due", "Priority", "Tags", "Project", "Recur", "RecurParent", "Parent", "Depends", "Status", "StatusChanges", "WaitUntil", "ModifiedAt", "CompletedAt", "Intervals", "Annotations", "DeletedAt", "UUID"}
+
+func taskToCSV(task Task) []string {
+	return []string{
+		strconv.Itoa(task.ID),
+		task.Task,
+		task.CreatedAt.UTC().Format(csvTimeLayout),
+		// Still written so older versions see closed tasks as complete
+		strconv.FormatBool(task.Closed()),
+		formatCSVTime(task.Due),
+		string(task.Priority),
+		strings.Join(task.Tags, " "),
+		task.Project,
+		task.Recur,
+		formatCSVID(task.RecurParent),
+		formatCSVID(task.Parent),
+		formatIDs(task.Depends),
+		string(task.Status),
+		formatStatusChanges(task.StatusChanges),
+		formatCSVTime(task.WaitUntil),
+		formatCSVTime(task.ModifiedAt),
+		formatCSVTime(task.CompletedAt),
+		formatIntervals(task.Intervals),
+		formatAnnotations(task.Annotations),
+		formatCSVTime(task.DeletedAt),
+		task.UUID,
+	}
 }
 
-func (s *CSVStorage) DeleteTask(w io.Writer, id int) error {
-	force := viper.GetBool("force")
-	if id <= 0 {
-		return fmt.Errorf("task ID must be greater than 0 %d", id)
+func taskFromCSV(record []string, columns map[string]int) (Task, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
 	}
 
-	tasks, err := readTasksCSV(s.filepath)
+	task, err := newTask(field("ID"), field("Task"), field("CreatedAt"), field("IsComplete"))
 	if err != nil {
-		return err // Error already formatted in readTasksCSV
-	}
-
-	var found bool
-
-	for i, task := range tasks {
-		if task.ID == id && !task.IsComplete {
-			for {
-				if !force {
-					fmt.Printf("Are you sure you want to delete this uncompleted task ([y]es | [n]o)? ")
-					reader := bufio.NewReader(os.Stdin)
-					input, err := reader.ReadString('\n')
-					if err != nil {
-						fmt.Println("An error occured while reading input. Please try again", err)
-						continue
-					}
-
-					input = strings.TrimSuffix(input, "\n") // remove trailing \n
-
-					if input == "no" || input == "n" {
-						return nil
-					}
-
-					if input == "yes" || input == "y" {
-						break // enter next conditional and delete the task
-					}
-				} else {
-					break
-				}
-			}
-		}
+		return Task{}, err
+	}
 
-		if task.ID == id {
-			fmt.Fprintln(w, "Deleting task:", task.Task)
-			tasks = slices.Delete(tasks, i, i+1) // delete current task
-			found = true
-			break
+	if task.Due, err = parseCSVTime(field("Due")); err != nil {
+		return Task{}, fmt.Errorf("error parsing due time: %w", err)
+	}
+	if task.Priority, err = ParsePriority(field("Priority")); err != nil {
+		return Task{}, err
+	}
+	task.Tags = NormalizeTags(strings.Fields(field("Tags")))
+	task.Project = field("Project")
+	task.Recur = field("Recur")
+	if task.RecurParent, err = parseCSVID(field("RecurParent")); err != nil {
+		return Task{}, fmt.Errorf("error converting recur parent to integer: %w", err)
+	}
+	if task.Parent, err = parseCSVID(field("Parent")); err != nil {
+		return Task{}, fmt.Errorf("error converting parent to integer: %w", err)
+	}
+	if task.Depends, err = parseIDs(field("Depends")); err != nil {
+		return Task{}, fmt.Errorf("error converting dependencies to integers: %w", err)
+	}
+	// Files written before the Status column only have IsComplete
+	if status := field("Status"); status != "" {
+		if task.Status, err = ParseStatus(status); err != nil {
+			return Task{}, err
 		}
 	}
-
-	if !found {
-		return fmt.Errorf("task with ID %d not found", id)
+	if task.StatusChanges, err = parseStatusChanges(field("StatusChanges")); err != nil {
+		return Task{}, err
 	}
-
-	return writeTasksCSV(s.filepath, tasks)
+	if task.WaitUntil, err = parseCSVTime(field("WaitUntil")); err != nil {
+		return Task{}, fmt.Errorf("error parsing wait until time: %w", err)
+	}
+	if task.ModifiedAt, err = parseCSVTime(field("ModifiedAt")); err != nil {
+		return Task{}, fmt.Errorf("error parsing modified at time: %w", err)
+	}
+	if task.CompletedAt, err = parseCSVTime(field("CompletedAt")); err != nil {
+		return Task{}, fmt.Errorf("error parsing completed at time: %w", err)
+	}
+	if task.Intervals, err = parseIntervals(field("Intervals")); err != nil {
+		return Task{}, err
+	}
+	if task.Annotations, err = parseAnnotations(field("Annotations")); err != nil {
+		return Task{}, err
+	}
+	if task.DeletedAt, err = parseCSVTime(field("DeletedAt")); err != nil {
+		return Task{}, fmt.Errorf("error parsing deleted at time: %w", err)
+	}
+	task.UUID = field("UUID")
+	return task, nil
 }
 
-func readTasksCSV(path string) ([]Task, error) {
-	file, err := LoadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("error loading file: %w", err)
+// Optional references to other tasks are left empty when unset
+func formatCSVID(id int) string {
+	if id == 0 {
+		return ""
 	}
-	defer CloseFile(file)
-
-	csvReader := csv.NewReader(file)
+	return strconv.Itoa(id)
+}
 
-	records, err := csvReader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("error reading CSV file: %w", err)
+func parseCSVID(value string) (int, error) {
+	if value == "" {
+		return 0, nil
 	}
+	return strconv.Atoi(value)
+}
 
-	// TODO: check whether if longer returning an error broke something
-	if len(records) == 0 {
-		return []Task{}, nil
+// Lists of IDs are stored space separated, like tags
+func formatIDs(ids []int) string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
 	}
+	return strings.Join(values, " ")
+}
 
-	var tasks []Task
-	for _, record := range records {
-		if record[0] == "ID" {
-			continue // Skip the header row
-		}
-		task, err := newTask(record[0], record[1], record[2], record[3])
+func parseIDs(value string) ([]int, error) {
+	var ids []int
+	for field := range strings.FieldsSeq(value) {
+		id, err := strconv.Atoi(field)
 		if err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, task)
+		ids = append(ids, id)
 	}
-
-	return tasks, nil
+	return NormalizeIDs(ids), nil
 }
 
-func writeTasksCSV(path string, tasks []Task) error {
-	file, err := LoadFile(path)
-	if err != nil {
-		return fmt.Errorf("error loading file: %w", err)
-	}
-	defer CloseFile(file)
+// RFC 1123 down to the nanosecond, so a task reads back exactly as it was
+// written. Older versions read it as RFC 1123, time.Parse takes a fraction of
+// a second after the seconds whatever the layout
+const csvTimeLayout = "Mon, 02 Jan 2006 15:04:05.999999999 MST"
 
-	if err := os.Truncate(path, 0); err != nil {
-		return fmt.Errorf("error truncating the file: %w", err)
-	}
-	csvWriter := csv.NewWriter(file)
-	if err := csvWriter.Write([]string{"ID", "Task", "CreatedAt", "IsComplete"}); err != nil {
-		return fmt.Errorf("error writing header to file: %w", err)
+// Optional times are left empty when unset
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
+	return t.UTC().Format(csvTimeLayout)
+}
 
-	for _, task := range tasks {
-		record := []string{
-			strconv.Itoa(task.ID),
-			task.Task,
-			task.CreatedAt.Format(time.RFC1123),
-			strconv.FormatBool(task.IsComplete),
-		}
-		// TODO: check this, because if it fails it will remove the file contents, or fill it halfway at the point it errors out
-		if err := csvWriter.Write(record); err != nil {
-			return fmt.Errorf("error writing task to file: %w", err)
-		}
+func parseCSVTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
 	}
-
-	csvWriter.Flush()
-	return csvWriter.Error()
+	return time.Parse(csvTimeLayout, value)
 }
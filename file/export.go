@@ -0,0 +1,145 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Formats tasks can be exported to and imported from
+var Formats = []string{"csv", "json"}
+
+// Guess the format of a file from its extension, empty if it is not one of Formats
+func FormatFromPath(path string) string {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if slices.Contains(Formats, ext) {
+		return ext
+	}
+	return ""
+}
+
+// Write snap to w in the same layout the CSV and JSON storages use
+func Encode(w io.Writer, format string, snap Snapshot) error {
+	switch format {
+	case "csv":
+		return writeTasksCSV(w, snap)
+	case "json":
+		data, err := json.MarshalIndent(&snap, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal tasks: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// Read a snapshot written by Encode or by the CSV and JSON storages
+func Decode(r io.Reader, format string) (Snapshot, error) {
+	switch format {
+	case "csv":
+		return readTasksCSV(r)
+	case "json":
+		return readTasksJSON(r)
+	default:
+		return Snapshot{}, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+type ImportResult struct {
+	// Contents of the store once the import is done
+	Snapshot Snapshot
+	// Number of tasks taken from the imported snapshot
+	Imported int
+	// Imported tasks that were given a different ID, old ID to new ID
+	Remapped map[int]int
+	// Imported tasks skipped because the store already had them
+	Duplicates []Task
+}
+
+// Combine the tasks of a store with imported ones.
+//
+// When merging, imported tasks are appended with IDs taken from the store
+// sequence, so they never collide with (or reuse) an existing ID. When
+// replacing, imported tasks keep their IDs and the existing ones are dropped,
+// only IDs repeated in the import itself are remapped.
+//
+// A task is a duplicate when another one has the same UUID, whatever its ID,
+// or the same description and was created at the same second (CSV only keeps
+// seconds). Recurring task templates are only duplicates of other templates
+func Import(current, incoming Snapshot, replace bool) ImportResult {
+	result := ImportResult{Remapped: map[int]int{}}
+
+	if replace {
+		result.Snapshot.LastID = max(current.LastID, incoming.LastID)
+		for _, task := range incoming.Tasks {
+			result.Snapshot.LastID = max(result.Snapshot.LastID, task.ID)
+		}
+	} else {
+		result.Snapshot.LastID = current.LastID
+		result.Snapshot.Tasks = slices.Clone(current.Tasks)
+	}
+
+	type key struct {
+		task     string
+		created  int64
+		template bool
+	}
+	seen := map[key]int{}
+	seenUUIDs := map[string]int{}
+	usedIDs := map[int]bool{}
+	for _, task := range result.Snapshot.Tasks {
+		seen[key{task.Task, task.CreatedAt.Unix(), task.IsTemplate()}] = task.ID
+		if task.UUID != "" {
+			seenUUIDs[task.UUID] = task.ID
+		}
+		usedIDs[task.ID] = true
+	}
+
+	// Where every imported task ended up, duplicates included, so references
+	// between imported tasks keep pointing at the same task
+	newIDs := map[int]int{}
+	first := len(result.Snapshot.Tasks)
+	for _, task := range incoming.Tasks {
+		k := key{task.Task, task.CreatedAt.Unix(), task.IsTemplate()}
+		id, ok := seen[k]
+		if uuidID, found := seenUUIDs[task.UUID]; found && task.UUID != "" {
+			id, ok = uuidID, true
+		}
+		if ok {
+			newIDs[task.ID] = id
+			result.Duplicates = append(result.Duplicates, task)
+			continue
+		}
+		oldID := task.ID
+
+		if !replace || task.ID <= 0 || usedIDs[task.ID] {
+			newID := result.Snapshot.nextID()
+			if newID != task.ID {
+				result.Remapped[task.ID] = newID
+			}
+			task.ID = newID
+		}
+		usedIDs[task.ID] = true
+		seen[k] = task.ID
+		if task.UUID != "" {
+			seenUUIDs[task.UUID] = task.ID
+		}
+		newIDs[oldID] = task.ID
+
+		result.Snapshot.Tasks = append(result.Snapshot.Tasks, task)
+		result.Imported++
+	}
+
+	for i := first; i < len(result.Snapshot.Tasks); i++ {
+		result.Snapshot.Tasks[i].remapIDs(newIDs)
+	}
+	return result
+}
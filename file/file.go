@@ -4,6 +4,7 @@ Copyright © 2025 Oscar Marquez
 package file
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"os"
@@ -15,18 +16,30 @@ import (
 // Check if the file exists, if not create it with read-write permissions
 func LoadFile(filename string) (*os.File, error) {
 	cleanFilename := filepath.Clean(filename)
-	file, err := os.OpenFile(cleanFilename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file for reading")
-	}
+	for {
+		file, err := os.OpenFile(cleanFilename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file for reading")
+		}
 
-	// Exclusive lock obtained on the file descriptor
-	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
-		_ = file.Close()
-		return nil, err
-	}
+		// Exclusive lock obtained on the file descriptor
+		if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
 
-	return file, nil
+		// While waiting for the lock another process may have replaced the file
+		// (see replaceFile), in which case the lock is held on a stale copy and
+		// the new file has to be opened again
+		current, err := os.Stat(cleanFilename)
+		if err == nil {
+			locked, err := file.Stat()
+			if err == nil && os.SameFile(current, locked) {
+				return file, nil
+			}
+		}
+		_ = CloseFile(file)
+	}
 }
 
 // Release the lock on the file descriptor and close the file
@@ -37,20 +50,139 @@ func CloseFile(file *os.File) error {
 	return file.Close()
 }
 
-type FileStorage interface {
-	AddTask(task string) error
-	ListTasks(w io.Writer) error
-	CompleteTask(w io.Writer, id int) error
-	DeleteTask(w io.Writer, id int) error
+// Atomically replace the contents of a file obtained from LoadFile with
+// whatever write produces. The new contents are written to a temporary file in
+// the same directory, synced and then renamed over the original, so a failure
+// at any point leaves the previous contents untouched. The previous generation
+// is kept next to it with a .bak extension.
+//
+// The lock on file must still be held, it is what keeps concurrent writers
+// from replacing the file at the same time
+func replaceFile(file *os.File, write func(w io.Writer) error) (err error) {
+	path := file.Name()
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	bufW := bufio.NewWriter(tmp)
+	if err := write(bufW); err != nil {
+		return err
+	}
+	if err := bufW.Flush(); err != nil {
+		return fmt.Errorf("error writing temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("error syncing temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temporary file: %w", err)
+	}
+
+	if err := backupFile(file); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing file: %w", err)
+	}
+
+	// Persist the rename itself
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("error opening directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("error syncing directory: %w", err)
+	}
+	return nil
+}
+
+// Keep the current contents of file as <name>.bak. A hard link is enough since
+// replaceFile never writes to the original file in place, copying is only the
+// fallback for filesystems that do not support links
+func backupFile(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("error getting file info: %w", err)
+	}
+	if info.Size() == 0 {
+		return nil // nothing worth keeping
+	}
+
+	backup := file.Name() + ".bak"
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing old backup: %w", err)
+	}
+	if err := os.Link(file.Name(), backup); err == nil {
+		return nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error seeking file: %w", err)
+	}
+	bak, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating backup: %w", err)
+	}
+	if _, err := io.Copy(bak, file); err != nil {
+		_ = bak.Close()
+		return fmt.Errorf("error writing backup: %w", err)
+	}
+	if err := bak.Sync(); err != nil {
+		_ = bak.Close()
+		return fmt.Errorf("error syncing backup: %w", err)
+	}
+	return bak.Close()
+}
+
+// Data access to the tasks, independent of how they are stored or presented.
+// Errors about specific tasks wrap ErrNotFound, ErrInvalidID or ErrEmptyTask
+type Repository interface {
+	Get(id int) (Task, error)
+	List(filter Filter) ([]Task, error)
+	// Store a new task and return it with its ID (and creation and
+	// modification times if they were not set) filled in
+	Create(task Task) (Task, error)
+	// Overwrite the task with the same ID, setting its modification time
+	Update(task Task) (Task, error)
+	Delete(id int) error
+	// Export every task in the store, completed ones included
+	Export() (Snapshot, error)
+	// Replace every task in the store with the ones in snap, keeping their IDs
+	Replace(snap Snapshot) error
+	// Let fn change every task in the store as a single change, so no other one
+	// can come in between reading the tasks and writing them back: it runs under
+	// the file lock, or in a SQLite transaction. Nothing is stored when fn
+	// fails
+	Transact(fn func(snap *Snapshot) error) error
+	// Tasks whose description, tags or annotations match query, completed ones
+	// included, the best matches first
+	Search(query SearchQuery) ([]SearchResult, error)
+	// Give the tasks consecutive IDs from 1, keeping their order and UUIDs, so
+	// the next task takes the one after the last. The reserved IDs, like those
+	// of archived tasks, are skipped and never handed out. References to other
+	// tasks follow. Returns the old ID to new ID of every task
+	Renumber(reserved []int) (map[int]int, error)
 }
 
-func SelectStorage(path, storageType string) (FileStorage, error) {
+func SelectStorage(path, storageType string) (Repository, error) {
 	switch storageType {
 	case "csv":
 		return NewCSVStorage(path + "." + storageType), nil
 	case "json":
 		return NewJSONStorage(path + "." + storageType), nil
-		// case "sqlite":
+	case "sqlite":
+		return NewSQLiteStorage(path + "." + storageType), nil
 	default:
 		return nil, fmt.Errorf("unsupported storage type: %s", storageType)
 	}
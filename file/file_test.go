@@ -0,0 +1,252 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_replaceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.csv")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	replace := func(content string, writeErr error) error {
+		file, err := LoadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer CloseFile(file)
+		return replaceFile(file, func(w io.Writer) error {
+			if _, err := io.WriteString(w, content); err != nil {
+				return err
+			}
+			return writeErr
+		})
+	}
+
+	if err := replace("new", nil); err != nil {
+		t.Fatalf("replaceFile() failed: %v", err)
+	}
+	assertContent(t, path, "new")
+	assertContent(t, path+".bak", "old")
+
+	// A failed write must leave both generations untouched
+	if err := replace("half", errors.New("disk full")); err == nil {
+		t.Fatal("replaceFile() succeeded unexpectedly")
+	}
+	assertContent(t, path, "new")
+	assertContent(t, path+".bak", "old")
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected only the file and its backup, got %d entries", len(entries))
+	}
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("%s = %q, want %q", filepath.Base(path), got, want)
+	}
+}
+
+func Test_Repository(t *testing.T) {
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			repo, err := SelectStorage(filepath.Join(dir, "tasks"), storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			first, err := repo.Create(Task{Task: "do the dishes"})
+			if err != nil {
+				t.Fatalf("Create() failed: %v", err)
+			}
+			second, err := repo.Create(Task{Task: "water the plants"})
+			if err != nil {
+				t.Fatalf("Create() failed: %v", err)
+			}
+			if first.ID != 1 || second.ID != 2 || first.CreatedAt.IsZero() {
+				t.Errorf("Create() = %+v, %+v, want IDs 1 and 2 with a creation time", first, second)
+			}
+
+			first.SetStatus(StatusDone, time.Now())
+			if _, err := repo.Update(first); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+			got, err := repo.Get(first.ID)
+			if err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			if !got.IsComplete() {
+				t.Errorf("Get() = %+v, want a completed task", got)
+			}
+
+			pending, err := repo.List(Filter{})
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			if len(pending) != 1 || pending[0].ID != second.ID {
+				t.Errorf("List() = %+v, want only task %d", pending, second.ID)
+			}
+			all, err := repo.List(Filter{All: true})
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			if len(all) != 2 {
+				t.Errorf("List(All) returned %d tasks, want 2", len(all))
+			}
+
+			// Deleted tasks are only listed on their own
+			second.DeletedAt = time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+			if _, err := repo.Update(second); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+			if all, err := repo.List(Filter{All: true}); err != nil || len(all) != 1 || all[0].ID != first.ID {
+				t.Errorf("List(All) = %+v, %v, want only task %d", all, err, first.ID)
+			}
+			deleted, err := repo.List(Filter{Deleted: true})
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			if len(deleted) != 1 || !deleted[0].DeletedAt.Equal(second.DeletedAt) {
+				t.Errorf("List(Deleted) = %+v, want task %d deleted at %v", deleted, second.ID, second.DeletedAt)
+			}
+
+			if err := repo.Delete(first.ID); err != nil {
+				t.Fatalf("Delete() failed: %v", err)
+			}
+			if _, err := repo.Get(first.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() of a deleted task = %v, want ErrNotFound", err)
+			}
+			if err := repo.Delete(first.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Delete() of a deleted task = %v, want ErrNotFound", err)
+			}
+			if _, err := repo.Update(Task{ID: 42, Task: "nope"}); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Update() of a missing task = %v, want ErrNotFound", err)
+			}
+			if _, err := repo.Get(0); !errors.Is(err, ErrInvalidID) {
+				t.Errorf("Get(0) = %v, want ErrInvalidID", err)
+			}
+			if _, err := repo.Create(Task{}); !errors.Is(err, ErrEmptyTask) {
+				t.Errorf("Create() without description = %v, want ErrEmptyTask", err)
+			}
+		})
+	}
+}
+
+func Test_Repository_Transact(t *testing.T) {
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			repo, err := SelectStorage(filepath.Join(dir, "tasks"), storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, task := range []string{"do the dishes", "water the plants", "call the bank"} {
+				if _, err := repo.Create(Task{Task: task}); err != nil {
+					t.Fatalf("Create() failed: %v", err)
+				}
+			}
+
+			failed := errors.New("failed")
+			err = repo.Transact(func(snap *Snapshot) error {
+				snap.Tasks = snap.Tasks[:1]
+				return failed
+			})
+			if !errors.Is(err, failed) {
+				t.Errorf("Transact() = %v, want the error of fn", err)
+			}
+			if all, _ := repo.List(Filter{All: true}); len(all) != 3 {
+				t.Errorf("List() after a failed Transact() = %+v, want every task", all)
+			}
+
+			err = repo.Transact(func(snap *Snapshot) error {
+				if _, err := snap.delete(1); err != nil {
+					return err
+				}
+				snap.Tasks[0].Tags = []string{"home"}
+				_, err := snap.create(Task{Task: "pay the rent"})
+				return err
+			})
+			if err != nil {
+				t.Fatalf("Transact() failed: %v", err)
+			}
+			all, err := repo.List(Filter{All: true})
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			if len(all) != 3 || all[0].ID != 2 || !all[0].HasTag("home") || all[2].ID != 4 || all[2].UUID == "" {
+				t.Errorf("List() after Transact() = %+v, want tasks 2 (tagged), 3 and 4", all)
+			}
+			if created, _ := repo.Create(Task{Task: "buy milk"}); created.ID != 5 {
+				t.Errorf("Create() after Transact() = %+v, want ID 5", created)
+			}
+
+			// Times are kept to the nanosecond, a change below a second is
+			// still a change
+			for _, change := range []func(task *Task){
+				func(task *Task) {
+					task.ModifiedAt = task.ModifiedAt.Truncate(time.Second).Add(time.Millisecond)
+				},
+				func(task *Task) {
+					now := time.Now()
+					task.Intervals = []Interval{{Start: now, End: now.Add(time.Millisecond)}}
+					task.StatusChanges = []StatusChange{{Status: StatusPending, At: now}}
+				},
+			} {
+				var changed Task
+				err = repo.Transact(func(snap *Snapshot) error {
+					change(&snap.Tasks[0])
+					changed = snap.Tasks[0].clone()
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("Transact() failed: %v", err)
+				}
+				if got, err := repo.Get(changed.ID); err != nil || !got.equal(changed) {
+					t.Errorf("Get() after Transact() = %+v, %v, want %+v", got, err, changed)
+				}
+			}
+		})
+	}
+}
+
+// Processes opening a new database at the same time each bring it up to date
+// without getting in the way of the others
+func TestSQLiteConcurrentMigrations(t *testing.T) {
+	for run := range 5 {
+		path := filepath.Join(t.TempDir(), "tasks")
+		errs := make(chan error, 8)
+		var wg sync.WaitGroup
+		for range cap(errs) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := NewSQLiteStorage(path + ".sqlite").List(Filter{All: true})
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("run %d: List() on a new database failed: %v", run, err)
+			}
+		}
+	}
+}
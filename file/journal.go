@@ -0,0 +1,516 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+var (
+	// Returned by Journal.Undo and Journal.Redo when a task was changed after
+	// the operation being undone or redone
+	ErrJournalConflict = errors.New("changed since")
+	// Returned by Journal.Undo when every operation was undone already
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// Returned by Journal.Redo when no operation was undone since the last change
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// Append-only log of every change made to the tasks of a store, so they can be
+// undone. It is kept as JSON lines in a file next to the tasks, one line per
+// changed task. Changes made by the same command share an operation number
+type Journal struct {
+	filepath string
+}
+
+// The journal of the tasks stored at path by SelectStorage
+func JournalPath(path, storageType string) string {
+	return path + "." + storageType + ".journal"
+}
+
+func NewJournal(filepath string) *Journal {
+	return &Journal{filepath: filepath}
+}
+
+// A change to a single task, as written to the journal
+type JournalEntry struct {
+	Op      int       `json:"op"`
+	At      time.Time `json:"at"`
+	Command string    `json:"command"`
+	// The operation tasks undo or redo undid or redid, on their own changes
+	Undoes int `json:"undoes,omitempty"`
+	Redoes int `json:"redoes,omitempty"`
+	ID     int `json:"id"`
+	// Nil when the task was created
+	Before *Task `json:"before"`
+	// Nil when the task was deleted
+	After *Task `json:"after"`
+	// The archive a deleted task was moved to, like
+	// tasks.archive-2025-06-18.csv, see Archive
+	Archive string `json:"archive,omitempty"`
+}
+
+// The changes made by a command, in the order they were made
+type Operation struct {
+	Op      int
+	At      time.Time
+	Command string
+	Undoes  int
+	Redoes  int
+	Changes []JournalEntry
+	// Undone and not redone since
+	Undone bool
+}
+
+// Read every entry of the journal, oldest first. A missing journal is empty
+func (j *Journal) Entries() ([]JournalEntry, error) {
+	f, err := os.Open(j.filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening journal: %w", err)
+	}
+	defer f.Close()
+	return readJournal(f)
+}
+
+func readJournal(r io.Reader) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(r)
+	// A line holds two whole tasks, notes and all
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error reading journal line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading journal: %w", err)
+	}
+	return entries, nil
+}
+
+// Every operation in the journal, oldest first
+func (j *Journal) Operations() ([]Operation, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []Operation
+	index := map[int]int{}
+	for _, entry := range entries {
+		i, ok := index[entry.Op]
+		if !ok {
+			i = len(ops)
+			index[entry.Op] = i
+			ops = append(ops, Operation{Op: entry.Op, At: entry.At, Command: entry.Command, Undoes: entry.Undoes, Redoes: entry.Redoes})
+		}
+		ops[i].Changes = append(ops[i].Changes, entry)
+	}
+
+	undoable, _ := undoStacks(ops)
+	for i := range ops {
+		ops[i].Undone = ops[i].Undoes == 0 && ops[i].Redoes == 0 && !slices.Contains(undoable, ops[i].Op)
+	}
+	return ops, nil
+}
+
+// Operations that can be undone and redone, the next one to undo or redo last.
+// Undoing an operation makes it the next one to redo, any other change forgets
+// what could be redone
+func undoStacks(ops []Operation) (undoable, redoable []int) {
+	for _, op := range ops {
+		switch {
+		case op.Undoes != 0:
+			undoable = slices.DeleteFunc(undoable, func(n int) bool { return n == op.Undoes })
+			redoable = append(redoable, op.Undoes)
+		case op.Redoes != 0:
+			redoable = slices.DeleteFunc(redoable, func(n int) bool { return n == op.Redoes })
+			undoable = append(undoable, op.Redoes)
+		default:
+			undoable = append(undoable, op.Op)
+			redoable = nil
+		}
+	}
+	return undoable, redoable
+}
+
+// Append entries to the journal, numbering them as the next operation when op
+// is zero. Returns the operation number
+func (j *Journal) append(op int, entries ...JournalEntry) (int, error) {
+	f, err := LoadFile(j.filepath)
+	if err != nil {
+		return 0, fmt.Errorf("error opening journal: %w", err)
+	}
+	defer CloseFile(f)
+
+	if op == 0 {
+		existing, err := readJournal(f)
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range existing {
+			op = max(op, entry.Op)
+		}
+		op++
+	}
+
+	w := bufio.NewWriter(f)
+	for _, entry := range entries {
+		entry.Op = op
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return 0, fmt.Errorf("error encoding journal entry: %w", err)
+		}
+		w.Write(data)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return 0, fmt.Errorf("error writing journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return 0, fmt.Errorf("error syncing journal: %w", err)
+	}
+	return op, nil
+}
+
+// Wrap repo so every change made through it is added to the journal, as a
+// single operation of command
+func (j *Journal) Record(repo Repository, command string) Repository {
+	return &journaledRepository{Repository: repo, journal: j, command: command}
+}
+
+type journaledRepository struct {
+	Repository
+	journal *Journal
+	command string
+	// Operation number of the changes, zero until the first one
+	op int
+	// The archive the tasks removed are moved to while archiving, see Archive
+	archive string
+}
+
+func (r *journaledRepository) record(changes ...JournalEntry) error {
+	for i := range changes {
+		changes[i].At, changes[i].Command = time.Now().UTC(), r.command
+	}
+	op, err := r.journal.append(r.op, changes...)
+	if err != nil {
+		return err
+	}
+	r.op = op
+	return nil
+}
+
+// Changes are made in Transact and recorded before they are stored, so the
+// task is recorded as it was right before, with no other change in between,
+// and a change is never stored without its entry
+func (r *journaledRepository) Create(task Task) (Task, error) {
+	var created Task
+	err := r.Repository.Transact(func(snap *Snapshot) error {
+		var err error
+		if created, err = snap.create(task); err != nil {
+			return err
+		}
+		return r.record(JournalEntry{ID: created.ID, After: &created})
+	})
+	return created, err
+}
+
+func (r *journaledRepository) Update(task Task) (Task, error) {
+	var updated Task
+	err := r.Repository.Transact(func(snap *Snapshot) error {
+		var before Task
+		if i := slices.IndexFunc(snap.Tasks, func(t Task) bool { return t.ID == task.ID }); i >= 0 {
+			before = snap.Tasks[i]
+		}
+		var err error
+		if updated, err = snap.update(task); err != nil {
+			return err
+		}
+		return r.record(JournalEntry{ID: updated.ID, Before: &before, After: &updated})
+	})
+	return updated, err
+}
+
+func (r *journaledRepository) Delete(id int) error {
+	return r.Repository.Transact(func(snap *Snapshot) error {
+		before, err := snap.delete(id)
+		if err != nil {
+			return err
+		}
+		return r.record(JournalEntry{ID: id, Before: &before})
+	})
+}
+
+// Only the tasks that changed are recorded
+func (r *journaledRepository) Replace(snap Snapshot) error {
+	return r.Transact(func(current *Snapshot) error {
+		// The ID sequence never goes backwards, see Repository.Replace
+		snap.LastID = max(snap.LastID, current.LastID)
+		snap.Tasks = slices.Clone(snap.Tasks)
+		*current = snap
+		return nil
+	})
+}
+
+// Only the tasks fn changed are recorded
+func (r *journaledRepository) Transact(fn func(snap *Snapshot) error) error {
+	return r.Repository.Transact(func(snap *Snapshot) error {
+		before := make([]Task, len(snap.Tasks))
+		for i, task := range snap.Tasks {
+			before[i] = task.clone()
+		}
+		if err := fn(snap); err != nil {
+			return err
+		}
+		assignUUIDs(snap.Tasks)
+		return r.recordChanges(before, snap.Tasks)
+	})
+}
+
+// Record the differences between two versions of the tasks, nothing when
+// they are the same
+func (r *journaledRepository) recordChanges(current, tasks []Task) error {
+	before := map[int]*Task{}
+	for i := range current {
+		before[current[i].ID] = &current[i]
+	}
+	var changes []JournalEntry
+	for i := range tasks {
+		after := &tasks[i]
+		if old := before[after.ID]; old == nil || !sameTask(*old, *after) {
+			changes = append(changes, JournalEntry{ID: after.ID, Before: old, After: after})
+		}
+		delete(before, after.ID)
+	}
+	for _, task := range current {
+		if old := before[task.ID]; old != nil {
+			changes = append(changes, JournalEntry{ID: task.ID, Before: old, Archive: r.archive})
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return r.record(changes...)
+}
+
+// Entries are rewritten with the new IDs, so the operations made before can
+// still be undone
+func (r *journaledRepository) Renumber(reserved []int) (map[int]int, error) {
+	ids, err := r.Repository.Renumber(reserved)
+	if err != nil {
+		return nil, err
+	}
+	return ids, r.journal.renumber(ids)
+}
+
+// Point every entry at the new IDs of the tasks, see Repository.Renumber
+func (j *Journal) renumber(ids map[int]int) error {
+	f, err := LoadFile(j.filepath)
+	if err != nil {
+		return fmt.Errorf("error opening journal: %w", err)
+	}
+	defer CloseFile(f)
+
+	entries, err := readJournal(f)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		if id, ok := ids[entry.ID]; ok {
+			entry.ID = id
+		}
+		for _, task := range []*Task{entry.Before, entry.After} {
+			if task == nil {
+				continue
+			}
+			if id, ok := ids[task.ID]; ok {
+				task.ID = id
+			}
+			task.renumber(ids)
+		}
+	}
+	return replaceFile(f, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := enc.Encode(entry); err != nil {
+				return fmt.Errorf("error encoding journal entry: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Revert the last n operations that were not undone yet, the most recent
+// first. Nothing changes when a task was changed after the operation that is
+// undone, unless force is set. Returns the undone operations
+func (j *Journal) Undo(repo Repository, n int, force bool) ([]Operation, error) {
+	return j.replay(repo, n, force, true)
+}
+
+// Make the last n undone operations again, the last undone first. Returns the
+// redone operations
+func (j *Journal) Redo(repo Repository, n int, force bool) ([]Operation, error) {
+	return j.replay(repo, n, force, false)
+}
+
+func (j *Journal) replay(repo Repository, n int, force, undo bool) ([]Operation, error) {
+	// Undoing is not an operation that can be undone itself
+	if r, ok := repo.(*journaledRepository); ok {
+		repo = r.Repository
+	}
+	ops, err := j.Operations()
+	if err != nil {
+		return nil, err
+	}
+	undoable, redoable := undoStacks(ops)
+	stack, errNothing, verb := redoable, ErrNothingToRedo, "redo"
+	if undo {
+		stack, errNothing, verb = undoable, ErrNothingToUndo, "undo"
+	}
+	if len(stack) == 0 {
+		return nil, errNothing
+	}
+
+	var selected []Operation
+	for _, number := range slices.Backward(stack[max(len(stack)-n, 0):]) {
+		i := slices.IndexFunc(ops, func(op Operation) bool { return op.Op == number })
+		selected = append(selected, ops[i])
+	}
+
+	// Recorded before the tasks are stored, like the changes of a
+	// journaledRepository. Archived tasks go back to their archive before they
+	// are removed, and come out of it once they are stored again, so a failure
+	// never loses them
+	unarchived := map[string][]Task{}
+	err = repo.Transact(func(snap *Snapshot) error {
+		var entries [][]JournalEntry
+		rearchived := map[string][]Task{}
+		for _, op := range selected {
+			changes := slices.Clone(op.Changes)
+			if undo {
+				slices.Reverse(changes)
+			}
+			var applied []JournalEntry
+			for _, change := range changes {
+				from, to := change.Before, change.After
+				if undo {
+					from, to = to, from
+				}
+				if err := applyChange(snap, change.ID, from, to, force); err != nil {
+					return fmt.Errorf("cannot %s operation %d (%s): %w", verb, op.Op, op.Command, err)
+				}
+				entry := JournalEntry{At: time.Now().UTC(), Command: verb, ID: change.ID, Before: from, After: to}
+				switch {
+				case change.Archive != "" && to == nil:
+					rearchived[change.Archive] = append(rearchived[change.Archive], *from)
+					entry.Archive = change.Archive
+				case change.Archive != "" && from == nil:
+					unarchived[change.Archive] = append(unarchived[change.Archive], *to)
+				}
+				if undo {
+					entry.Undoes = op.Op
+				} else {
+					entry.Redoes = op.Op
+				}
+				applied = append(applied, entry)
+			}
+			entries = append(entries, applied)
+		}
+		// Only once every change applies, as nothing is stored otherwise
+		for _, file := range slices.Sorted(maps.Keys(rearchived)) {
+			if err := rearchive(file, rearchived[file]); err != nil {
+				return fmt.Errorf("error writing archive %s: %w", file, err)
+			}
+		}
+		for _, applied := range entries {
+			if _, err := j.append(0, applied...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range slices.Sorted(maps.Keys(unarchived)) {
+		if err := unarchive(file, unarchived[file]); err != nil {
+			return nil, fmt.Errorf("error writing archive %s: %w", file, err)
+		}
+	}
+	return selected, nil
+}
+
+// Whether two versions of a task are the same, see Task.equal. Entries written
+// before UUIDs existed have none, which is not a change
+func sameTask(a, b Task) bool {
+	if a.UUID == "" || b.UUID == "" {
+		a.UUID, b.UUID = "", ""
+	}
+	return a.equal(b)
+}
+
+// Move task id of snap from one state to another, nil meaning it does not
+// exist. Unless force is set, the task has to be in the from state. Tasks are
+// found by their UUID, which renumbering does not change, and a task that
+// comes back takes a new ID when its own was given to another task since
+func applyChange(snap *Snapshot, id int, from, to *Task, force bool) error {
+	var uuid string
+	for _, task := range []*Task{from, to} {
+		if task != nil && task.UUID != "" {
+			uuid = task.UUID
+			break
+		}
+	}
+	i := slices.IndexFunc(snap.Tasks, func(task Task) bool { return task.ID == id })
+	if uuid != "" {
+		i = slices.IndexFunc(snap.Tasks, func(task Task) bool { return task.UUID == uuid })
+	}
+	if !force {
+		switch {
+		case from == nil && i >= 0:
+			return fmt.Errorf("task %d %w", id, ErrJournalConflict)
+		case from != nil && i < 0:
+			return &NotFoundError{ID: id}
+		case from != nil && !sameTask(snap.Tasks[i], *from):
+			return fmt.Errorf("task %d %w", id, ErrJournalConflict)
+		}
+	}
+
+	switch {
+	case to == nil && i >= 0:
+		snap.Tasks = slices.Delete(snap.Tasks, i, i+1)
+	case to != nil && i >= 0:
+		task := *to
+		task.ID = snap.Tasks[i].ID
+		task.UUID = cmp.Or(task.UUID, snap.Tasks[i].UUID)
+		snap.Tasks[i] = task
+	case to != nil:
+		task := *to
+		if slices.ContainsFunc(snap.Tasks, func(t Task) bool { return t.ID == task.ID }) {
+			task.ID = snap.nextID()
+		}
+		snap.Tasks = append(snap.Tasks, task)
+		slices.SortFunc(snap.Tasks, func(a, b Task) int { return a.ID - b.ID })
+	}
+	return nil
+}
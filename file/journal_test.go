@@ -0,0 +1,210 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			path := filepath.Join(dir, "tasks")
+			store, err := SelectStorage(path, storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			journal := NewJournal(JournalPath(path, storageType))
+			// A new wrapper for every command, like the CLI does
+			repo := func(command string) Repository { return journal.Record(store, command) }
+
+			milk, err := repo("add").Create(Task{Task: "buy milk"})
+			if err != nil {
+				t.Fatalf("Create() failed: %v", err)
+			}
+			bank, err := repo("add").Create(Task{Task: "call the bank"})
+			if err != nil {
+				t.Fatalf("Create() failed: %v", err)
+			}
+			milk.SetStatus(StatusDone, now)
+			if _, err := repo("complete").Update(milk); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+			if err := repo("delete").Delete(bank.ID); err != nil {
+				t.Fatalf("Delete() failed: %v", err)
+			}
+
+			ops, err := journal.Operations()
+			if err != nil {
+				t.Fatalf("Operations() failed: %v", err)
+			}
+			var commands []string
+			for _, op := range ops {
+				commands = append(commands, op.Command)
+			}
+			if want := []string{"add", "add", "complete", "delete"}; !slices.Equal(commands, want) {
+				t.Fatalf("Operations() = %q, want %q", commands, want)
+			}
+			if change := ops[3].Changes[0]; change.ID != bank.ID || change.Before == nil || change.Before.Task != "call the bank" || change.After != nil {
+				t.Errorf("delete change = %+v, want the deleted task before", change)
+			}
+
+			// The deleted task comes back with its ID, then the completion is reverted
+			undone, err := journal.Undo(repo("undo"), 2, false)
+			if err != nil || len(undone) != 2 || undone[0].Command != "delete" {
+				t.Fatalf("Undo() = %+v, %v, want the delete and complete operations", undone, err)
+			}
+			restored, err := store.Get(bank.ID)
+			if err != nil || restored.Task != "call the bank" {
+				t.Errorf("Get() after undo = %+v, %v, want the deleted task back", restored, err)
+			}
+			if got, _ := store.Get(milk.ID); got.Status != StatusPending || !got.CompletedAt.IsZero() {
+				t.Errorf("Get() after undo = %+v, want a pending task", got)
+			}
+
+			if _, err := journal.Redo(store, 1, false); err != nil {
+				t.Fatalf("Redo() failed: %v", err)
+			}
+			if got, _ := store.Get(milk.ID); got.Status != StatusDone {
+				t.Errorf("Get() after redo = %+v, want a done task", got)
+			}
+
+			// Any other change forgets what could be redone
+			restored.Task = "call the bank about the fee"
+			if _, err := repo("modify").Update(restored); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+			if _, err := journal.Redo(store, 1, false); !errors.Is(err, ErrNothingToRedo) {
+				t.Errorf("Redo() after a change = %v, want ErrNothingToRedo", err)
+			}
+			if _, err := journal.Undo(store, 1, false); err != nil {
+				t.Fatalf("Undo() of the last change failed: %v", err)
+			}
+			// Changed without the journal, like an older version would
+			restored.Task = "call the bank again"
+			if _, err := store.Update(restored); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+			// The completion, then the creation of the changed task
+			if _, err := journal.Undo(store, 2, false); !errors.Is(err, ErrJournalConflict) {
+				t.Errorf("Undo() over a changed task = %v, want ErrJournalConflict", err)
+			}
+			if got, _ := store.Get(milk.ID); got.Status != StatusDone {
+				t.Errorf("Get() after a failed undo = %+v, want nothing undone", got)
+			}
+			if _, err := journal.Undo(store, 1, false); err != nil {
+				t.Fatalf("Undo() of the completion failed: %v", err)
+			}
+			if _, err := journal.Undo(store, 1, true); err != nil {
+				t.Fatalf("Undo() with force failed: %v", err)
+			}
+			if _, err := store.Get(bank.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() after a forced undo = %v, want ErrNotFound", err)
+			}
+
+			snap, err := store.Export()
+			if err != nil {
+				t.Fatalf("Export() failed: %v", err)
+			}
+			snap.Tasks = nil
+			if err := repo("import").Replace(snap); err != nil {
+				t.Fatalf("Replace() failed: %v", err)
+			}
+			ops, err = journal.Operations()
+			if err != nil {
+				t.Fatalf("Operations() failed: %v", err)
+			}
+			if last := ops[len(ops)-1]; last.Command != "import" || len(last.Changes) != 1 || last.Changes[0].ID != milk.ID {
+				t.Errorf("Replace() recorded %+v, want the removed task", last)
+			}
+		})
+	}
+}
+
+// A task changed since it was recorded is a conflict, however small the change
+func TestJournal_SubSecondConflict(t *testing.T) {
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			path := filepath.Join(dir, "tasks")
+			store, err := SelectStorage(path, storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			journal := NewJournal(JournalPath(path, storageType))
+			task, err := journal.Record(store, "add").Create(Task{Task: "buy milk"})
+			if err != nil {
+				t.Fatalf("Create() failed: %v", err)
+			}
+
+			err = store.Transact(func(snap *Snapshot) error {
+				snap.Tasks[0].ModifiedAt = snap.Tasks[0].ModifiedAt.Add(time.Nanosecond)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Transact() failed: %v", err)
+			}
+			if _, err := journal.Undo(store, 1, false); !errors.Is(err, ErrJournalConflict) {
+				t.Errorf("Undo() over a task changed by a nanosecond = %v, want ErrJournalConflict", err)
+			}
+			if _, err := store.Get(task.ID); err != nil {
+				t.Errorf("Get() after a failed undo = %v, want the task kept", err)
+			}
+		})
+	}
+}
+
+func TestJournal_ConcurrentChanges(t *testing.T) {
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			path := filepath.Join(dir, "tasks")
+			store, err := SelectStorage(path, storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			journal := NewJournal(JournalPath(path, storageType))
+			task, err := journal.Record(store, "add").Create(Task{Task: "buy milk"})
+			if err != nil {
+				t.Fatalf("Create() failed: %v", err)
+			}
+
+			// Every change is recorded against the one right before it
+			var wg sync.WaitGroup
+			for i := range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					changed := task
+					changed.Task = fmt.Sprintf("buy milk %d", i)
+					if _, err := journal.Record(store, "modify").Update(changed); err != nil {
+						t.Errorf("Update() failed: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			entries, err := journal.Entries()
+			if err != nil {
+				t.Fatalf("Entries() failed: %v", err)
+			}
+			if len(entries) != 9 {
+				t.Fatalf("Entries() returned %d entries, want 9", len(entries))
+			}
+			for i := 1; i < len(entries); i++ {
+				if entries[i].Before == nil || entries[i].Before.Task != entries[i-1].After.Task {
+					t.Errorf("entry %d changed %+v, want the task as entry %d left it: %+v", i, entries[i].Before, i-1, entries[i-1].After)
+				}
+			}
+			if got, _ := store.Get(task.ID); got.Task != entries[8].After.Task {
+				t.Errorf("Get() = %+v, want the task as the last entry left it", got)
+			}
+		})
+	}
+}
@@ -4,203 +4,76 @@ Copyright © 2025 Oscar Marquez
 package file
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
-	"slices"
-	"strings"
-	"time"
-
-	"github.com/spf13/viper"
 )
 
 type JSONStorage struct {
-	filepath string
+	fileStore
 }
 
 func NewJSONStorage(filepath string) *JSONStorage {
 	return &JSONStorage{
-		filepath: filepath,
+		fileStore{
+			filepath: filepath,
+			read:     readTasksJSON,
+			write:    writeTasksJSON,
+		},
 	}
 }
 
-func (s *JSONStorage) AddTask(task string) error {
-	tasks, err := readTasksJSON(s.filepath)
-	if err != nil {
-		return err
-	}
-
-	lastID := 1
-	if len(tasks) != 0 {
-		lastID = tasks[len(tasks)-1].ID // should be last task
-	}
-
-	tasks = append(tasks, Task{
-		ID:         lastID,
-		Task:       task,
-		CreatedAt:  time.Now().UTC(),
-		IsComplete: false,
-	})
+func readTasksJSON(r io.Reader) (Snapshot, error) {
+	var snap Snapshot
 
-	data, err := json.Marshal(&tasks)
+	fileContent, err := io.ReadAll(r)
 	if err != nil {
-		return fmt.Errorf("could not marshal tasks: %w", err)
+		return Snapshot{}, fmt.Errorf("error reading file: %w", err)
 	}
 
-	if err := os.WriteFile(s.filepath, data, 0600); err != nil {
-		return fmt.Errorf("could not write data to file: %w", err)
-	}
-	return nil
-}
-
-func (s *JSONStorage) ListTasks(w io.Writer) error {
-	tasks, err := readTasksJSON(s.filepath)
-	if err != nil {
-		return err
+	fileContent = bytes.TrimSpace(fileContent)
+	if len(fileContent) == 0 {
+		return snap, nil
 	}
 
-	if len(tasks) == 0 {
-		fmt.Fprintln(w, "No tasks found in the file: header only")
-		return nil
+	// Files written before the metadata existed are a bare array of tasks
+	if fileContent[0] == '[' {
+		err = json.Unmarshal(fileContent, &snap.Tasks)
+	} else {
+		err = json.Unmarshal(fileContent, &snap)
 	}
-
-	fmt.Fprintln(w, "Total tasks:", len(tasks))
-	printTasks(w, tasks)
-
-	return nil
-}
-
-func (s *JSONStorage) CompleteTask(w io.Writer, id int) error {
-	if id <= 0 {
-		return fmt.Errorf("task ID must be greater than 0, got %d", id)
-	}
-
-	found := false
-	tasks, err := readTasksJSON(s.filepath)
 	if err != nil {
-		return err
-	}
-
-	for i, task := range tasks {
-		if task.ID == id && task.IsComplete {
-			return fmt.Errorf("task with ID %d is already completed", id)
-		}
-		if task.ID == id {
-			fmt.Fprintln(w, "Completing task:", task.Task)
-			tasks[i].IsComplete = true
-			found = true
-			break
-		}
+		return Snapshot{}, fmt.Errorf("error unmarshalling file: %w", err)
 	}
 
-	if !found {
-		return fmt.Errorf("task with ID %d not found", id)
-	}
-	return writeTasksJSON(s.filepath, tasks)
+	return snap, nil
 }
 
-func (s *JSONStorage) DeleteTask(w io.Writer, id int) error {
-	force := viper.GetBool("force")
-	if id <= 0 {
-		return fmt.Errorf("task ID must be greater than 0 %d", id)
-	}
-
-	tasks, err := readTasksJSON(s.filepath)
+func writeTasksJSON(w io.Writer, snap Snapshot) error {
+	data, err := json.Marshal(&snap)
 	if err != nil {
-		return err
-	}
-
-	var found bool
-
-	for i, task := range tasks {
-		if task.ID == id && !task.IsComplete {
-			for {
-				if !force {
-					fmt.Printf("Are you sure you want to delete this uncompleted task ([y]es | [n]o)? ")
-					reader := bufio.NewReader(os.Stdin)
-					input, err := reader.ReadString('\n')
-					if err != nil {
-						fmt.Println("An error occured while reading input. Please try again", err)
-						continue
-					}
-
-					input = strings.TrimSuffix(input, "\n") // remove trailing \n
-
-					if input == "no" || input == "n" {
-						return nil
-					}
-
-					if input == "yes" || input == "y" {
-						break // enter next conditional and delete the task
-					}
-				} else {
-					break
-				}
-			}
-		}
-
-		if task.ID == id {
-			fmt.Fprintln(w, "Deleting task:", task.Task)
-			tasks = slices.Delete(tasks, i, i+1) // delete current task
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		return fmt.Errorf("task with ID %d not found", id)
-	}
-
-	return writeTasksJSON(s.filepath, tasks)
-}
-
-func readTasksJSON(path string) ([]Task, error) {
-	var tasks []Task
-	file, err := LoadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("error loading file: %w", err)
-	}
-	defer CloseFile(file)
-
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("error getting file info: %w", err)
-	}
-
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
-	}
-
-	if fileInfo.Size() == 0 {
-		return []Task{}, nil // no tasks, does nil work here?
-	}
-
-	if err := json.Unmarshal(fileContent, &tasks); err != nil {
-		return nil, fmt.Errorf("error unmarshalling file: %w", err)
+		return fmt.Errorf("could not marshal tasks: %w", err)
 	}
 
-	return tasks, nil
+	_, err = w.Write(data)
+	return err
 }
 
-func writeTasksJSON(path string, tasks []Task) error {
-	file, err := LoadFile(path)
-	if err != nil {
-		return fmt.Errorf("error loading file: %w", err)
+// Tasks written before statuses existed only have IsComplete
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type task Task // Without this method, so it does not recurse
+	aux := struct {
+		task
+		IsComplete bool
+	}{}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
 	}
-	defer CloseFile(file)
 
-	if err := os.Truncate(path, 0); err != nil {
-		return fmt.Errorf("error truncating the file: %w", err)
+	*t = Task(aux.task)
+	if t.Status == "" {
+		t.Status = legacyStatus(aux.IsComplete)
 	}
-
-	// TODO: check this, because if it fails it will remove the file contents, or fill it halfway at the point it errors out
-	data, err := json.Marshal(&tasks)
-	if err != nil {
-		return fmt.Errorf("could not marshal tasks: %w", err)
-	}
-
-	return os.WriteFile(path, data, 0600)
+	return nil
 }
@@ -0,0 +1,369 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How often a recurring task repeats. It is a subset of the iCalendar RRULE
+// (FREQ, INTERVAL, BYDAY, BYMONTHDAY and UNTIL) that ParseRecurrence also
+// builds from shorthands like weekly or every 2w
+type Recurrence struct {
+	// daily, weekly, monthly or yearly
+	Freq string
+	// Number of Freq periods between occurrences, at least 1
+	Interval int
+	// Only these days of the week (BYDAY), in daily and weekly recurrences
+	Weekdays []time.Weekday
+	// Only these days of the month (BYMONTHDAY), in monthly recurrences
+	MonthDays []int
+	// No occurrence after this time, zero when it repeats forever
+	Until time.Time
+}
+
+var recurFreqs = map[string]string{
+	"daily":   "daily",
+	"day":     "daily",
+	"d":       "daily",
+	"weekly":  "weekly",
+	"week":    "weekly",
+	"w":       "weekly",
+	"monthly": "monthly",
+	"month":   "monthly",
+	"mo":      "monthly",
+	"yearly":  "yearly",
+	"year":    "yearly",
+	"y":       "yearly",
+}
+
+var rruleWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+var workWeek = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+// Accepts daily, weekdays, weekly, monthly, yearly, "every <n><d|w|mo|y>" (or
+// with the unit spelled out, like "every 3 days") and RRULE strings like
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", with or without the RRULE: prefix
+func ParseRecurrence(s string) (Recurrence, error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+
+	if lower == "weekdays" {
+		return Recurrence{Freq: "daily", Interval: 1, Weekdays: workWeek}, nil
+	}
+	if freq, ok := recurFreqs[lower]; ok && len(lower) > 2 {
+		return Recurrence{Freq: freq, Interval: 1}, nil
+	}
+	if every, ok := strings.CutPrefix(lower, "every "); ok {
+		return parseEvery(strings.TrimSpace(every))
+	}
+	if strings.Contains(lower, "freq=") {
+		return parseRRule(s)
+	}
+	return Recurrence{}, fmt.Errorf("invalid recurrence %q, use daily, weekdays, weekly, monthly, yearly, every 2w or an RRULE", s)
+}
+
+// "2w", "2 weeks", "day" or "3mo"
+func parseEvery(s string) (Recurrence, error) {
+	digits := strings.TrimLeft(s, "0123456789")
+	n := 1
+	if number := strings.TrimSpace(s[:len(s)-len(digits)]); number != "" {
+		var err error
+		if n, err = strconv.Atoi(number); err != nil || n < 1 {
+			return Recurrence{}, fmt.Errorf("invalid recurrence interval %q", number)
+		}
+	}
+
+	unit := strings.TrimSuffix(strings.TrimSpace(digits), "s")
+	freq, ok := recurFreqs[unit]
+	if !ok {
+		return Recurrence{}, fmt.Errorf("invalid recurrence unit %q, use d, w, mo or y", digits)
+	}
+	return Recurrence{Freq: freq, Interval: n}, nil
+}
+
+func parseRRule(s string) (Recurrence, error) {
+	r := Recurrence{Interval: 1}
+	rule := strings.TrimPrefix(strings.ToUpper(s), "RRULE:")
+	for part := range strings.SplitSeq(rule, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "FREQ":
+			r.Freq = strings.ToLower(value)
+			if !slices.Contains([]string{"daily", "weekly", "monthly", "yearly"}, r.Freq) {
+				return Recurrence{}, fmt.Errorf("unsupported RRULE frequency %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return Recurrence{}, fmt.Errorf("invalid RRULE interval %q", value)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for day := range strings.SplitSeq(value, ",") {
+				i := slices.Index(rruleWeekdays, day)
+				if i < 0 {
+					return Recurrence{}, fmt.Errorf("invalid RRULE day %q, use MO, TU, WE, TH, FR, SA or SU", day)
+				}
+				r.Weekdays = append(r.Weekdays, time.Weekday(i))
+			}
+		case "BYMONTHDAY":
+			for day := range strings.SplitSeq(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n < 1 || n > 31 {
+					return Recurrence{}, fmt.Errorf("invalid RRULE day of the month %q", day)
+				}
+				r.MonthDays = append(r.MonthDays, n)
+			}
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return Recurrence{}, fmt.Errorf("invalid RRULE until %q", value)
+			}
+			r.Until = until
+		case "WKST":
+			// Weeks always start on Monday
+		default:
+			return Recurrence{}, fmt.Errorf("unsupported RRULE part %q", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return Recurrence{}, fmt.Errorf("RRULE %q has no FREQ", s)
+	}
+	if len(r.Weekdays) > 0 && r.Freq != "daily" && r.Freq != "weekly" {
+		return Recurrence{}, fmt.Errorf("BYDAY is only supported with daily and weekly recurrences")
+	}
+	if len(r.MonthDays) > 0 && r.Freq != "monthly" {
+		return Recurrence{}, fmt.Errorf("BYMONTHDAY is only supported with monthly recurrences")
+	}
+	r.Weekdays = slices.Compact(slices.Sorted(slices.Values(r.Weekdays)))
+	r.MonthDays = slices.Compact(slices.Sorted(slices.Values(r.MonthDays)))
+	return r, nil
+}
+
+// UNTIL is either a date or a UTC date-time
+func parseRRuleTime(s string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("20060102", s, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// The whole last day is included
+	return t.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+// The shorthand ParseRecurrence accepts for r when there is one, otherwise
+// an RRULE
+func (r Recurrence) String() string {
+	if r.Until.IsZero() && len(r.MonthDays) == 0 {
+		switch {
+		case len(r.Weekdays) == 0 && r.Interval == 1:
+			return r.Freq
+		case r.Freq == "daily" && r.Interval == 1 && slices.Equal(r.Weekdays, workWeek):
+			return "weekdays"
+		case len(r.Weekdays) == 0:
+			unit := map[string]string{"daily": "d", "weekly": "w", "monthly": "mo", "yearly": "y"}[r.Freq]
+			return fmt.Sprintf("every %d%s", r.Interval, unit)
+		}
+	}
+
+	rule := "FREQ=" + strings.ToUpper(r.Freq)
+	if r.Interval > 1 {
+		rule += ";INTERVAL=" + strconv.Itoa(r.Interval)
+	}
+	if len(r.Weekdays) > 0 {
+		days := make([]string, len(r.Weekdays))
+		for i, day := range r.Weekdays {
+			days[i] = rruleWeekdays[day]
+		}
+		rule += ";BYDAY=" + strings.Join(days, ",")
+	}
+	if len(r.MonthDays) > 0 {
+		days := make([]string, len(r.MonthDays))
+		for i, day := range r.MonthDays {
+			days[i] = strconv.Itoa(day)
+		}
+		rule += ";BYMONTHDAY=" + strings.Join(days, ",")
+	}
+	if !r.Until.IsZero() {
+		rule += ";UNTIL=" + r.Until.UTC().Format("20060102T150405Z")
+	}
+	return rule
+}
+
+// The first occurrence strictly after t, keeping its time of the day. The
+// second result is false once the recurrence is over
+func (r Recurrence) Next(t time.Time) (time.Time, bool) {
+	var next time.Time
+	switch {
+	case len(r.Weekdays) > 0 && r.Freq == "weekly":
+		next = r.nextWeekday(t)
+	case len(r.Weekdays) > 0:
+		// Some intervals never land on the listed days, like every 7 days on
+		// Mondays starting on a Tuesday, those just repeat every Interval days
+		next = t.AddDate(0, 0, r.Interval)
+		for i := 0; i < 7 && !slices.Contains(r.Weekdays, next.Weekday()); i++ {
+			next = next.AddDate(0, 0, r.Interval)
+		}
+	case len(r.MonthDays) > 0:
+		next = r.nextMonthDay(t)
+	case r.Freq == "daily":
+		next = t.AddDate(0, 0, r.Interval)
+	case r.Freq == "weekly":
+		next = t.AddDate(0, 0, 7*r.Interval)
+	case r.Freq == "monthly":
+		next = addMonths(t, r.Interval, t.Day())
+	case r.Freq == "yearly":
+		next = addMonths(t, 12*r.Interval, t.Day())
+	}
+
+	if !r.Until.IsZero() && next.After(r.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// The next listed weekday later in the same week, or the first one of the
+// week Interval weeks later. Weeks start on Monday
+func (r Recurrence) nextWeekday(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	for _, day := range r.mondayFirst() {
+		if d := (int(day) + 6) % 7; d > offset {
+			return t.AddDate(0, 0, d-offset)
+		}
+	}
+	first := (int(r.mondayFirst()[0]) + 6) % 7
+	return t.AddDate(0, 0, 7*r.Interval-offset+first)
+}
+
+func (r Recurrence) mondayFirst() []time.Weekday {
+	days := slices.Clone(r.Weekdays)
+	slices.SortFunc(days, func(a, b time.Weekday) int { return (int(a)+6)%7 - (int(b)+6)%7 })
+	return days
+}
+
+// The next listed day later in the same month, or the first one that exists
+// Interval months later
+func (r Recurrence) nextMonthDay(t time.Time) time.Time {
+	for _, day := range r.MonthDays {
+		if day > t.Day() && day <= daysIn(t.Year(), t.Month()) {
+			return t.AddDate(0, 0, day-t.Day())
+		}
+	}
+	for months := r.Interval; ; months += r.Interval {
+		first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		for _, day := range r.MonthDays {
+			if day <= daysIn(first.Year(), first.Month()) {
+				return first.AddDate(0, 0, day-1)
+			}
+		}
+	}
+}
+
+// Move t by months, on day or on the last day of the month when it is
+// shorter. Unlike time.AddDate, January 31 plus a month is February 28
+func addMonths(t time.Time, months, day int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	return first.AddDate(0, 0, min(day, daysIn(first.Year(), first.Month()))-1)
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// A recurring task is made of a template, which holds the recurrence and is
+// hidden from the task list, and of its instances, which are regular tasks
+// pointing at the template with RecurParent. Only one instance is pending at a
+// time, the next one is created when it is completed
+func (t Task) IsTemplate() bool {
+	return t.Recur != "" && t.RecurParent == 0
+}
+
+// Mark task id as done. When it is an instance of a recurring task whose
+// template still exists, the next instance is created with the following due
+// date, skipping the occurrences that are already in the past. Both happen as
+// a single change to repo, see Repository.Transact. next has a zero ID when no
+// instance was created
+func CompleteTask(repo Repository, id int, now time.Time) (done, next Task, err error) {
+	err = repo.Transact(func(snap *Snapshot) error {
+		i := slices.IndexFunc(snap.Tasks, func(task Task) bool { return task.ID == id })
+		if i < 0 {
+			return &NotFoundError{ID: id}
+		}
+		task := snap.Tasks[i]
+		if task.Closed() {
+			return fmt.Errorf("task with ID %d is already %s", id, task.Status)
+		}
+		task.SetStatus(StatusDone, now)
+		if done, err = snap.update(task); err != nil {
+			return err
+		}
+
+		next, err = nextInstance(*snap, done, now)
+		if err != nil || next.Task == "" {
+			return err
+		}
+		next, err = snap.create(next)
+		return err
+	})
+	if err != nil {
+		return Task{}, Task{}, err
+	}
+	return done, next, nil
+}
+
+// The instance of a recurring task that follows the one done, none when the
+// recurrence is over
+func nextInstance(snap Snapshot, done Task, now time.Time) (Task, error) {
+	if done.RecurParent == 0 {
+		return Task{}, nil
+	}
+	i := slices.IndexFunc(snap.Tasks, func(task Task) bool { return task.ID == done.RecurParent })
+	if i < 0 || snap.Tasks[i].Deleted() {
+		// Deleting the template stops the recurrence
+		return Task{}, nil
+	}
+	template := snap.Tasks[i]
+	recur, err := ParseRecurrence(template.Recur)
+	if err != nil {
+		return Task{}, err
+	}
+
+	// Weekdays and days of the month are those of the local calendar
+	due := done.Due.Local()
+	if done.Due.IsZero() {
+		due = now
+	}
+	due, ok := recur.Next(due)
+	for ok && !due.After(now) {
+		due, ok = recur.Next(due)
+	}
+	if !ok {
+		return Task{}, nil
+	}
+
+	next := done
+	next.ID = 0
+	next.CreatedAt = now.UTC()
+	next.ModifiedAt = time.Time{}
+	next.Status = StatusPending
+	next.StatusChanges = nil
+	next.WaitUntil = time.Time{}
+	next.CompletedAt = time.Time{}
+	next.Intervals = nil
+	next.Annotations = nil
+	next.Due = due.UTC()
+	next.Recur = template.Recur
+	return next, nil
+}
@@ -0,0 +1,136 @@
+package file
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "daily", want: "daily"},
+		{spec: "Weekly", want: "weekly"},
+		{spec: "weekdays", want: "weekdays"},
+		{spec: "every 2w", want: "every 2w"},
+		{spec: "every 3 days", want: "every 3d"},
+		{spec: "every month", want: "monthly"},
+		{spec: "FREQ=WEEKLY;INTERVAL=2", want: "every 2w"},
+		{spec: "RRULE:FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", want: "weekdays"},
+		{spec: "freq=weekly;byday=th,mo", want: "FREQ=WEEKLY;BYDAY=MO,TH"},
+		{spec: "FREQ=MONTHLY;BYMONTHDAY=15,1;UNTIL=20250101T000000Z", want: "FREQ=MONTHLY;BYMONTHDAY=1,15;UNTIL=20250101T000000Z"},
+		{spec: "sometimes", wantErr: true},
+		{spec: "every 0d", wantErr: true},
+		{spec: "every 2 fortnights", wantErr: true},
+		{spec: "FREQ=HOURLY", wantErr: true},
+		{spec: "FREQ=MONTHLY;BYDAY=MO", wantErr: true},
+		{spec: "FREQ=DAILY;COUNT=3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseRecurrence(tt.spec)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("ParseRecurrence() failed: %v", err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatalf("ParseRecurrence() = %v, want an error", got)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ParseRecurrence().String() = %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrence_Next(t *testing.T) {
+	// A Friday
+	friday := time.Date(2025, 6, 20, 17, 0, 0, 0, time.UTC)
+	tests := []struct {
+		spec   string
+		from   time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{spec: "daily", from: friday, want: friday.AddDate(0, 0, 1), wantOK: true},
+		{spec: "weekdays", from: friday, want: friday.AddDate(0, 0, 3), wantOK: true},
+		{spec: "every 2w", from: friday, want: friday.AddDate(0, 0, 14), wantOK: true},
+		{spec: "FREQ=WEEKLY;BYDAY=MO,FR", from: friday, want: friday.AddDate(0, 0, 3), wantOK: true},
+		{spec: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", from: friday, want: friday.AddDate(0, 0, 10), wantOK: true},
+		{spec: "FREQ=WEEKLY;BYDAY=SU", from: friday, want: friday.AddDate(0, 0, 2), wantOK: true},
+		{spec: "monthly", from: time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC), want: time.Date(2025, 2, 28, 9, 0, 0, 0, time.UTC), wantOK: true},
+		{spec: "yearly", from: time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC), want: time.Date(2025, 2, 28, 9, 0, 0, 0, time.UTC), wantOK: true},
+		{spec: "FREQ=MONTHLY;BYMONTHDAY=1,15", from: friday, want: time.Date(2025, 7, 1, 17, 0, 0, 0, time.UTC), wantOK: true},
+		{spec: "FREQ=MONTHLY;BYMONTHDAY=31", from: time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC), want: time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC), wantOK: true},
+		{spec: "FREQ=DAILY;UNTIL=20250621T000000Z", from: friday, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseRecurrence() failed: %v", err)
+			}
+			got, ok := r.Next(tt.from)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCompleteTask(t *testing.T) {
+	repo := NewJSONStorage(filepath.Join(t.TempDir(), "tasks.json"))
+	now := time.Now()
+
+	template, err := repo.Create(Task{Task: "water the plants", Recur: "every 3d"})
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	// Completed late, the missed occurrence is skipped
+	instance, err := repo.Create(Task{Task: "water the plants", Recur: "every 3d", RecurParent: template.ID, Due: now.AddDate(0, 0, -4), Tags: []string{"home"}})
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	// Changes made after the task was read are kept
+	instance.Tags = []string{"garden"}
+	if _, err := repo.Update(instance); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	done, next, err := CompleteTask(repo, instance.ID, now)
+	if err != nil {
+		t.Fatalf("CompleteTask() failed: %v", err)
+	}
+	if next.ID == 0 || next.RecurParent != template.ID || next.Status != StatusPending || len(next.StatusChanges) > 0 || !next.HasTag("garden") {
+		t.Errorf("CompleteTask() = %+v, want a pending instance of task %d", next, template.ID)
+	}
+	if want := instance.Due.AddDate(0, 0, 6); !next.Due.Equal(want) {
+		t.Errorf("CompleteTask() due = %v, want %v", next.Due, want)
+	}
+	if got, _ := repo.Get(instance.ID); !got.IsComplete() || !got.HasTag("garden") || !sameTask(got, done) {
+		t.Errorf("Get() = %+v, want the completed task %+v", got, done)
+	}
+	if pending, _ := repo.List(Filter{}); len(pending) != 1 || pending[0].ID != next.ID {
+		t.Errorf("List() = %+v, want only the next instance", pending)
+	}
+
+	// Without its template a task no longer recurs
+	if err := repo.Delete(template.ID); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	_, last, err := CompleteTask(repo, next.ID, now)
+	if err != nil {
+		t.Fatalf("CompleteTask() failed: %v", err)
+	}
+	if last.ID != 0 {
+		t.Errorf("CompleteTask() = %+v, want no new instance", last)
+	}
+	if _, _, err := CompleteTask(repo, next.ID, now); err == nil {
+		t.Error("CompleteTask() of a done task succeeded, want an error")
+	}
+}
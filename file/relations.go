@@ -0,0 +1,165 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Check that parent exists and that making it the parent of task id does not
+// create a loop, like a task being a subtask of its own subtask. A zero parent
+// is always valid
+func ValidateParent(repo Repository, id, parent int) error {
+	seen := map[int]bool{}
+	for p := parent; p != 0 && !seen[p]; {
+		if p == id {
+			return fmt.Errorf("task %d cannot be a subtask of itself or of one of its subtasks", id)
+		}
+		seen[p] = true
+
+		task, err := repo.Get(p)
+		if err != nil {
+			return fmt.Errorf("invalid parent: %w", err)
+		}
+		if task.Deleted() {
+			return fmt.Errorf("invalid parent: task %d is deleted", p)
+		}
+		p = task.Parent
+	}
+	return nil
+}
+
+// Every subtask of task id among tasks, down to the last level, children
+// before grandchildren
+func Descendants(tasks []Task, id int) []Task {
+	var descendants []Task
+	parents := []int{id}
+	seen := map[int]bool{id: true}
+	for len(parents) > 0 {
+		var next []int
+		for _, task := range tasks {
+			if task.Parent != 0 && !seen[task.ID] && slices.Contains(parents, task.Parent) {
+				seen[task.ID] = true
+				descendants = append(descendants, task)
+				next = append(next, task.ID)
+			}
+		}
+		parents = next
+	}
+	return descendants
+}
+
+// Check that every dependency of task id exists and that none of them depends,
+// directly or not, on the task itself. A task id of zero is a task that does
+// not exist yet, which nothing can depend on. Further down the chain, missing
+// and deleted tasks are left out instead, the dependencies of those were not
+// for this task to check
+func ValidateDependencies(repo Repository, id int, depends []int) error {
+	seen := map[int]bool{}
+	var visit func(dep int, path []int) error
+	visit = func(dep int, path []int) error {
+		path = append(path, dep)
+		if dep == id {
+			return fmt.Errorf("dependency cycle: %s", formatCycle(path))
+		}
+		if seen[dep] {
+			return nil
+		}
+		seen[dep] = true
+
+		direct := len(path) == 2
+		task, err := repo.Get(dep)
+		switch {
+		case errors.Is(err, ErrNotFound) && !direct:
+			return nil
+		case err != nil:
+			return fmt.Errorf("invalid dependency: %w", err)
+		case task.Deleted() && !direct:
+			return nil
+		case task.Deleted():
+			return fmt.Errorf("invalid dependency: task %d is deleted", dep)
+		}
+		for _, next := range task.Depends {
+			if err := visit(next, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, dep := range depends {
+		if err := visit(dep, []int{id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove the tasks with the given IDs for good, along with the references other
+// tasks make to them, as a single change: their subtasks move to the top level,
+// dependencies on them are dropped and the instances of a purged recurring
+// task template no longer recur
+func Purge(repo Repository, ids []int) error {
+	return repo.Transact(func(snap *Snapshot) error {
+		purged := map[int]bool{}
+		for _, id := range ids {
+			if _, err := snap.delete(id); err != nil {
+				return err
+			}
+			purged[id] = true
+		}
+		now := time.Now().UTC()
+		for i := range snap.Tasks {
+			if snap.Tasks[i].dropReferences(func(id int) bool { return purged[id] }) {
+				snap.Tasks[i].ModifiedAt = now
+			}
+		}
+		return nil
+	})
+}
+
+// Drop the references to the tasks gone reports, returning whether there were
+// any. Without its template, a recurring task instance no longer recurs
+func (t *Task) dropReferences(gone func(id int) bool) bool {
+	changed := false
+	if t.Parent != 0 && gone(t.Parent) {
+		t.Parent = 0
+		changed = true
+	}
+	if t.RecurParent != 0 && gone(t.RecurParent) {
+		t.Recur, t.RecurParent = "", 0
+		changed = true
+	}
+	if slices.ContainsFunc(t.Depends, gone) {
+		t.Depends = NormalizeIDs(slices.DeleteFunc(slices.Clone(t.Depends), gone))
+		changed = true
+	}
+	return changed
+}
+
+func formatCycle(path []int) string {
+	ids := make([]string, len(path))
+	for i, id := range path {
+		ids[i] = strconv.Itoa(id)
+	}
+	return strings.Join(ids, " -> ")
+}
+
+// The dependencies of task that are still open among tasks, the task is
+// blocked until there are none. Dependencies on deleted tasks never block
+func BlockingTasks(tasks []Task, task Task) []int {
+	var blocking []int
+	for _, dep := range task.Depends {
+		i := slices.IndexFunc(tasks, func(t Task) bool { return t.ID == dep })
+		if i >= 0 && !tasks[i].Closed() {
+			blocking = append(blocking, dep)
+		}
+	}
+	return blocking
+}
@@ -0,0 +1,158 @@
+package file
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDescendants(t *testing.T) {
+	tasks := []Task{
+		{ID: 1},
+		{ID: 2, Parent: 1},
+		{ID: 3, Parent: 4},
+		{ID: 4, Parent: 1},
+		{ID: 5, Parent: 3},
+		{ID: 6},
+	}
+	var got []int
+	for _, task := range Descendants(tasks, 1) {
+		got = append(got, task.ID)
+	}
+	if want := []int{2, 4, 3, 5}; !slices.Equal(got, want) {
+		t.Errorf("Descendants() = %v, want %v", got, want)
+	}
+	if got := Descendants(tasks, 6); len(got) != 0 {
+		t.Errorf("Descendants() = %v, want none", got)
+	}
+}
+
+func TestValidateParent(t *testing.T) {
+	repo := NewCSVStorage(filepath.Join(t.TempDir(), "tasks.csv"))
+	for _, task := range []Task{{Task: "release"}, {Task: "tests", Parent: 1}, {Task: "unit tests", Parent: 2}} {
+		if _, err := repo.Create(task); err != nil {
+			t.Fatalf("Create() failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		id, parent int
+		wantErr    bool
+	}{
+		{id: 3, parent: 1},
+		{id: 1, parent: 0},
+		{id: 0, parent: 3},
+		{id: 1, parent: 1, wantErr: true},
+		{id: 1, parent: 3, wantErr: true},
+		{id: 3, parent: 42, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := ValidateParent(repo, tt.id, tt.parent); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateParent(%d, %d) = %v, want error %v", tt.id, tt.parent, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	repo := NewJSONStorage(filepath.Join(t.TempDir(), "tasks.json"))
+	for _, task := range []Task{{Task: "build"}, {Task: "test", Depends: []int{1}}, {Task: "deploy", Depends: []int{2}}} {
+		if _, err := repo.Create(task); err != nil {
+			t.Fatalf("Create() failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		id      int
+		depends []int
+		wantErr bool
+	}{
+		{id: 0, depends: []int{3}},
+		{id: 3, depends: []int{1, 2}},
+		{id: 1, depends: nil},
+		{id: 1, depends: []int{1}, wantErr: true},
+		{id: 1, depends: []int{3}, wantErr: true},
+		{id: 2, depends: []int{42}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := ValidateDependencies(repo, tt.id, tt.depends); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateDependencies(%d, %v) = %v, want error %v", tt.id, tt.depends, err, tt.wantErr)
+		}
+	}
+
+	// Only the tasks depended on directly have to be there
+	build, _ := repo.Get(1)
+	build.DeletedAt = time.Now()
+	if _, err := repo.Update(build); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if err := ValidateDependencies(repo, 0, []int{2}); err != nil {
+		t.Errorf("ValidateDependencies() over a deleted task = %v, want no error", err)
+	}
+	if err := ValidateDependencies(repo, 0, []int{1}); err == nil {
+		t.Error("ValidateDependencies() on a deleted task succeeded, want an error")
+	}
+	if err := repo.Delete(1); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if err := ValidateDependencies(repo, 0, []int{3}); err != nil {
+		t.Errorf("ValidateDependencies() over a missing task = %v, want no error", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	repo := NewJSONStorage(filepath.Join(t.TempDir(), "tasks.json"))
+	for _, task := range []Task{
+		{Task: "release"},
+		{Task: "tests", Parent: 1},
+		{Task: "deploy", Depends: []int{1, 2}},
+		{Task: "standup", Recur: "daily"},
+		{Task: "standup", Recur: "daily", RecurParent: 4},
+	} {
+		if _, err := repo.Create(task); err != nil {
+			t.Fatalf("Create() failed: %v", err)
+		}
+	}
+
+	if err := Purge(repo, []int{1, 4}); err != nil {
+		t.Fatalf("Purge() failed: %v", err)
+	}
+	tasks, err := repo.List(Filter{All: true})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("List() after Purge() = %+v, want 3 tasks", tasks)
+	}
+	if tests := tasks[0]; tests.Parent != 0 {
+		t.Errorf("subtask after Purge() = %+v, want it at the top level", tests)
+	}
+	if deploy := tasks[1]; !slices.Equal(deploy.Depends, []int{2}) {
+		t.Errorf("dependent task after Purge() = %+v, want it to depend on task 2 only", deploy)
+	}
+	if standup := tasks[2]; standup.Recur != "" || standup.RecurParent != 0 {
+		t.Errorf("instance after Purge() = %+v, want it to no longer recur", standup)
+	}
+	if err := Purge(repo, []int{2, 42}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Purge() of a missing task = %v, want ErrNotFound", err)
+	}
+	if _, err := repo.Get(2); err != nil {
+		t.Errorf("Get() after a failed Purge() = %v, want nothing purged", err)
+	}
+}
+
+func TestBlockingTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Status: StatusDone},
+		{ID: 4, Status: StatusCancelled},
+		{ID: 2},
+		{ID: 3, Depends: []int{1, 2, 4, 42}},
+	}
+	if got := BlockingTasks(tasks, tasks[3]); !slices.Equal(got, []int{2}) {
+		t.Errorf("BlockingTasks() = %v, want [2]", got)
+	}
+	if got := BlockingTasks(tasks, tasks[2]); got != nil {
+		t.Errorf("BlockingTasks() = %v, want none", got)
+	}
+}
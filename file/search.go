@@ -0,0 +1,255 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// How the text of a SearchQuery is matched
+type SearchMode string
+
+const (
+	// Case-insensitive substring, the default
+	SearchSubstring SearchMode = "substring"
+	// Go regular expression (https://pkg.go.dev/regexp/syntax), case-insensitive
+	// unless it starts with (?-i)
+	SearchRegex SearchMode = "regex"
+	// The characters of the text in order, with anything in between, like
+	// "rlsv2" for "Release v2"
+	SearchFuzzy SearchMode = "fuzzy"
+)
+
+// Fields a search looks at, in the order results list their matches
+const (
+	SearchFieldDescription = "description"
+	SearchFieldTag         = "tag"
+	SearchFieldAnnotation  = "annotation"
+)
+
+// Matches in descriptions count more than in tags, which count more than in
+// annotations
+var searchFieldWeights = map[string]float64{
+	SearchFieldDescription: 3,
+	SearchFieldTag:         2,
+	SearchFieldAnnotation:  1,
+}
+
+type SearchQuery struct {
+	Text string
+	Mode SearchMode
+}
+
+// A task that matches a search, the best matches have the highest score
+type SearchResult struct {
+	Task    Task
+	Score   float64
+	Matches []SearchMatch
+}
+
+// Where a field of a task matched
+type SearchMatch struct {
+	Field string
+	// The whole value of the field, like the text of an annotation
+	Text string
+	// Byte offsets of the matched parts of Text, sorted and not overlapping
+	Spans [][2]int
+}
+
+// Finds the parts of a text that match and how well they do
+type searchMatcher func(text string) (spans [][2]int, score float64)
+
+func (q SearchQuery) matcher() (searchMatcher, error) {
+	if strings.TrimSpace(q.Text) == "" {
+		return nil, fmt.Errorf("search text is empty")
+	}
+	switch cmp.Or(q.Mode, SearchSubstring) {
+	case SearchSubstring:
+		return regexpMatcher(regexp.MustCompile("(?i)" + regexp.QuoteMeta(q.Text))), nil
+	case SearchRegex:
+		re, err := regexp.Compile("(?i)" + q.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return regexpMatcher(re), nil
+	case SearchFuzzy:
+		return fuzzyMatcher([]rune(strings.ToLower(q.Text))), nil
+	default:
+		return nil, fmt.Errorf("invalid search mode %q", q.Mode)
+	}
+}
+
+// Every match counts, more so when it starts a word
+func regexpMatcher(re *regexp.Regexp) searchMatcher {
+	return func(text string) ([][2]int, float64) {
+		var spans [][2]int
+		var score float64
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if loc[0] == loc[1] {
+				continue // empty matches of patterns like a*
+			}
+			spans = append(spans, [2]int{loc[0], loc[1]})
+			score++
+			if wordStart(text, loc[0]) {
+				score += 0.5
+			}
+		}
+		return spans, score
+	}
+}
+
+// Characters are matched as early as possible. Runs of consecutive characters
+// and characters that start a word make a better match, a long text with the
+// characters spread apart a worse one
+func fuzzyMatcher(query []rune) searchMatcher {
+	return func(text string) ([][2]int, float64) {
+		var spans [][2]int
+		var score float64
+		q := 0
+		prev := -1
+		for i, r := range text {
+			if q == len(query) {
+				break
+			}
+			if unicode.ToLower(r) != query[q] {
+				continue
+			}
+			end := i + utf8.RuneLen(r)
+			if prev == i {
+				spans[len(spans)-1][1] = end
+				score += 2
+			} else {
+				spans = append(spans, [2]int{i, end})
+				score++
+			}
+			if wordStart(text, i) {
+				score++
+			}
+			prev = end
+			q++
+		}
+		if q < len(query) {
+			return nil, 0
+		}
+		// Long queries would otherwise outweigh the other fields
+		return spans, score / float64(len(query))
+	}
+}
+
+// Whether the byte at i starts a word of text
+func wordStart(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Match the description, tags and annotations of task
+func searchTask(task Task, match searchMatcher) (SearchResult, bool) {
+	result := SearchResult{Task: task}
+	add := func(field, text string) {
+		spans, score := match(text)
+		if len(spans) == 0 {
+			return
+		}
+		result.Matches = append(result.Matches, SearchMatch{Field: field, Text: text, Spans: spans})
+		result.Score += score * searchFieldWeights[field]
+	}
+
+	add(SearchFieldDescription, task.Task)
+	for _, tag := range task.Tags {
+		add(SearchFieldTag, tag)
+	}
+	for _, note := range task.Annotations {
+		add(SearchFieldAnnotation, note.Text)
+	}
+	return result, len(result.Matches) > 0
+}
+
+// Match every task, the best results first and the most recent ones first
+// among equally good results
+func searchTasks(tasks []Task, query SearchQuery) ([]SearchResult, error) {
+	match, err := query.matcher()
+	if err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, task := range tasks {
+		if task.Deleted() {
+			continue
+		}
+		if result, ok := searchTask(task, match); ok {
+			results = append(results, result)
+		}
+	}
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(b.Task.ID, a.Task.ID))
+	})
+	return results, nil
+}
+
+// Trigram index of the text searched in each task, so a substring search only
+// looks at the tasks that have every trigram of the text
+type searchIndex struct {
+	tasks []Task
+	// Positions in tasks, in ascending order
+	trigrams map[string][]int
+}
+
+func newSearchIndex(tasks []Task) *searchIndex {
+	idx := &searchIndex{tasks: tasks, trigrams: map[string][]int{}}
+	for i, task := range tasks {
+		texts := []string{task.Task}
+		texts = append(texts, task.Tags...)
+		for _, note := range task.Annotations {
+			texts = append(texts, note.Text)
+		}
+		for trigram := range trigrams(strings.ToLower(strings.Join(texts, "\n"))) {
+			idx.trigrams[trigram] = append(idx.trigrams[trigram], i)
+		}
+	}
+	return idx
+}
+
+// Tasks that may contain text, every task when it is shorter than a trigram
+func (idx *searchIndex) candidates(text string) []Task {
+	var positions []int
+	first := true
+	for trigram := range trigrams(strings.ToLower(text)) {
+		if first {
+			positions, first = idx.trigrams[trigram], false
+			continue
+		}
+		other := idx.trigrams[trigram]
+		positions = slices.DeleteFunc(slices.Clone(positions), func(i int) bool {
+			_, found := slices.BinarySearch(other, i)
+			return !found
+		})
+	}
+	if first {
+		return idx.tasks
+	}
+	tasks := make([]Task, len(positions))
+	for i, pos := range positions {
+		tasks[i] = idx.tasks[pos]
+	}
+	return tasks
+}
+
+// Every distinct sequence of three runes of text
+func trigrams(text string) map[string]struct{} {
+	runes := []rune(text)
+	set := map[string]struct{}{}
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,99 @@
+package file
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSearch(t *testing.T) {
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			repo, err := SelectStorage(filepath.Join(dir, "tasks"), storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, task := range []Task{
+				{Task: "Release v2 of the app", Tags: []string{"work"}},
+				{Task: "Fix the login page", Tags: []string{"bug", "release"}},
+				{Task: "Call the bank", Annotations: []Annotation{{At: now, Text: "ask about the\n\"release\" fee"}}},
+				{Task: "Water the plants", Status: StatusDone},
+			} {
+				if _, err := repo.Create(task); err != nil {
+					t.Fatalf("Create() failed: %v", err)
+				}
+			}
+
+			ids := func(query SearchQuery) []int {
+				t.Helper()
+				results, err := repo.Search(query)
+				if err != nil {
+					t.Fatalf("Search(%+v) failed: %v", query, err)
+				}
+				var ids []int
+				for _, result := range results {
+					ids = append(ids, result.Task.ID)
+				}
+				return ids
+			}
+
+			tests := []struct {
+				query SearchQuery
+				want  []int
+			}{
+				// Descriptions first, then tags, then annotations
+				{SearchQuery{Text: "RELEASE"}, []int{1, 2, 3}},
+				{SearchQuery{Text: `"release"`}, []int{3}},
+				// More matches, then the most recent
+				{SearchQuery{Text: "th", Mode: SearchSubstring}, []int{3, 4, 2, 1}},
+				{SearchQuery{Text: "plants"}, []int{4}},
+				{SearchQuery{Text: "nothing like it"}, nil},
+				{SearchQuery{Text: `^(fix|call)\b`, Mode: SearchRegex}, []int{3, 2}},
+				{SearchQuery{Text: "rlsv2", Mode: SearchFuzzy}, []int{1}},
+				{SearchQuery{Text: "bug", Mode: SearchFuzzy}, []int{2}},
+			}
+			for _, tt := range tests {
+				if got := ids(tt.query); !slices.Equal(got, tt.want) {
+					t.Errorf("Search(%+v) = %v, want %v", tt.query, got, tt.want)
+				}
+			}
+
+			results, err := repo.Search(SearchQuery{Text: "login"})
+			if err != nil || len(results) != 1 {
+				t.Fatalf("Search() = %v, %v, want one result", results, err)
+			}
+			want := []SearchMatch{{Field: SearchFieldDescription, Text: "Fix the login page", Spans: [][2]int{{8, 13}}}}
+			if got := results[0].Matches; len(got) != 1 || got[0].Field != want[0].Field || !slices.Equal(got[0].Spans, want[0].Spans) {
+				t.Errorf("Matches = %+v, want %+v", got, want)
+			}
+
+			// The index follows changes
+			task, err := repo.Get(2)
+			if err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			task.Task = "Fix the signup page"
+			if _, err := repo.Update(task); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+			if err := repo.Delete(1); err != nil {
+				t.Fatalf("Delete() failed: %v", err)
+			}
+			if got := ids(SearchQuery{Text: "signup"}); !slices.Equal(got, []int{2}) {
+				t.Errorf("Search() after update = %v, want [2]", got)
+			}
+			if got := ids(SearchQuery{Text: "release"}); !slices.Equal(got, []int{2, 3}) {
+				t.Errorf("Search() after delete = %v, want [2 3]", got)
+			}
+
+			for _, query := range []SearchQuery{{Text: " "}, {Text: "(", Mode: SearchRegex}, {Text: "x", Mode: "soundex"}} {
+				if _, err := repo.Search(query); err == nil {
+					t.Errorf("Search(%+v) should fail", query)
+				}
+			}
+		})
+	}
+}
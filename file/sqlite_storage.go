@@ -0,0 +1,687 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	_ "modernc.org/sqlite" // pure Go driver, no cgo needed
+)
+
+// Timestamps are stored as fixed-width UTC text so they sort lexicographically
+// and stay readable from the sqlite3 shell
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Every entry upgrades the schema by one version, the index + 1 of the last
+// applied migration is kept in PRAGMA user_version. Never edit an entry that
+// has already been released, append a new one instead.
+//
+// AUTOINCREMENT keeps the highest ID ever handed out in sqlite_sequence, which
+// is what Snapshot.LastID does for CSV and JSON files: IDs are never reused.
+var sqliteMigrations = []string{
+	`CREATE TABLE tasks (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		task        TEXT    NOT NULL CHECK (task <> ''),
+		created_at  TEXT    NOT NULL,
+		is_complete INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX idx_tasks_is_complete ON tasks (is_complete);
+	CREATE INDEX idx_tasks_created_at ON tasks (created_at);`,
+	`ALTER TABLE tasks ADD COLUMN due TEXT;
+	CREATE INDEX idx_tasks_due ON tasks (due);`,
+	`ALTER TABLE tasks ADD COLUMN priority TEXT NOT NULL DEFAULT '' CHECK (priority IN ('', 'L', 'M', 'H'));`,
+	// Tags are kept space separated, like in CSV files
+	`ALTER TABLE tasks ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+	ALTER TABLE tasks ADD COLUMN project TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_tasks_project ON tasks (project);`,
+	// recur_parent is 0 for tasks that are not a recurring task instance
+	`ALTER TABLE tasks ADD COLUMN recur TEXT NOT NULL DEFAULT '';
+	ALTER TABLE tasks ADD COLUMN recur_parent INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE tasks ADD COLUMN parent INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX idx_tasks_parent ON tasks (parent);`,
+	// Dependencies are kept space separated, like tags
+	`ALTER TABLE tasks ADD COLUMN depends TEXT NOT NULL DEFAULT '';`,
+	// is_complete is still written for older versions, it is set for closed tasks
+	`ALTER TABLE tasks ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'
+		CHECK (status IN ('pending', 'started', 'waiting', 'blocked', 'done', 'cancelled'));
+	ALTER TABLE tasks ADD COLUMN status_changes TEXT NOT NULL DEFAULT '';
+	ALTER TABLE tasks ADD COLUMN wait_until TEXT;
+	UPDATE tasks SET status = 'done' WHERE is_complete = 1;
+	CREATE INDEX idx_tasks_status ON tasks (status);`,
+	// Tasks changed before modified_at existed were last changed when created
+	// as far as anyone knows
+	`ALTER TABLE tasks ADD COLUMN modified_at TEXT;
+	ALTER TABLE tasks ADD COLUMN completed_at TEXT;
+	UPDATE tasks SET modified_at = created_at;
+	CREATE INDEX idx_tasks_completed_at ON tasks (completed_at);`,
+	// Time tracking intervals are kept space separated, like status changes
+	`ALTER TABLE tasks ADD COLUMN intervals TEXT NOT NULL DEFAULT '';`,
+	// A JSON array, see formatAnnotations
+	`ALTER TABLE tasks ADD COLUMN annotations TEXT NOT NULL DEFAULT '';`,
+	// Full-text index of what tasks search looks at, kept up to date by the
+	// triggers. The trigram tokenizer finds any substring of three characters or
+	// more, not only whole words. notes holds the text of the annotations
+	`CREATE VIRTUAL TABLE tasks_fts USING fts5(task, tags, notes, tokenize = 'trigram');
+	CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+		INSERT INTO tasks_fts (rowid, task, tags, notes) VALUES (new.id, new.task, new.tags, (
+			SELECT group_concat(json_extract(value, '$.Text'), char(10))
+			FROM json_each(CASE new.annotations WHEN '' THEN '[]' ELSE new.annotations END)
+		));
+	END;
+	CREATE TRIGGER tasks_fts_update AFTER UPDATE OF task, tags, annotations ON tasks BEGIN
+		UPDATE tasks_fts SET task = new.task, tags = new.tags, notes = (
+			SELECT group_concat(json_extract(value, '$.Text'), char(10))
+			FROM json_each(CASE new.annotations WHEN '' THEN '[]' ELSE new.annotations END)
+		) WHERE rowid = old.id;
+	END;
+	CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+		DELETE FROM tasks_fts WHERE rowid = old.id;
+	END;
+	INSERT INTO tasks_fts (rowid, task, tags, notes)
+		SELECT id, task, tags, (
+			SELECT group_concat(json_extract(value, '$.Text'), char(10))
+			FROM json_each(CASE annotations WHEN '' THEN '[]' ELSE annotations END)
+		) FROM tasks;`,
+	// Deleted tasks stay in the table until they are purged
+	`ALTER TABLE tasks ADD COLUMN deleted_at TEXT;
+	CREATE INDEX idx_tasks_deleted_at ON tasks (deleted_at);`,
+	// Random (version 4) UUIDs for the existing tasks, like newUUID
+	`ALTER TABLE tasks ADD COLUMN uuid TEXT;
+	UPDATE tasks SET uuid = lower(
+		hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+		substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))
+	);
+	CREATE UNIQUE INDEX idx_tasks_uuid ON tasks (uuid);`,
+}
+
+type SQLiteStorage struct {
+	filepath string
+}
+
+func NewSQLiteStorage(filepath string) *SQLiteStorage {
+	return &SQLiteStorage{
+		filepath: filepath,
+	}
+}
+
+// Open the database and bring its schema up to date. The caller is
+// responsible for closing it
+func (s *SQLiteStorage) open() (*sql.DB, error) {
+	// busy_timeout makes concurrent invocations wait for each other instead of
+	// failing right away with SQLITE_BUSY, much like the flock on CSV/JSON files.
+	// Transactions take the write lock as they begin, otherwise two of them
+	// could read the same tasks and only one of them could write
+	dsn := "file:" + s.filepath + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	if err := migrateSQLite(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Apply the migrations the database is missing. Each one runs in a
+// transaction that holds the write lock from the start and reads the version
+// again, so processes opening a new database at the same time do not apply the
+// same migration twice
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+	for version < len(sqliteMigrations) {
+		var err error
+		if version, err = applySQLiteMigration(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply the migration that follows the version of the database, if any, and
+// return the version it is at
+func applySQLiteMigration(db *sql.DB) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting migration: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op once committed
+
+	var version int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("error reading schema version: %w", err)
+	}
+	if version >= len(sqliteMigrations) {
+		return version, nil
+	}
+	if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+		return 0, fmt.Errorf("error applying migration %d: %w", version+1, err)
+	}
+	// PRAGMA does not accept bound parameters
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+		return 0, fmt.Errorf("error updating schema version: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing migration %d: %w", version+1, err)
+	}
+	return version + 1, nil
+}
+
+// Columns of the tasks table in the order sqliteValues and scanSQLiteTask use.
+// uuid is last, Update never changes it
+var sqliteColumns = []string{"id", "task", "created_at", "is_complete", "due", "priority", "tags", "project", "recur", "recur_parent", "parent", "depends", "status", "status_changes", "wait_until", "modified_at", "completed_at", "intervals", "annotations", "deleted_at", "uuid"}
+
+var (
+	sqliteSelect = "SELECT " + strings.Join(sqliteColumns, ", ") + " FROM tasks"
+	sqliteInsert = "INSERT INTO tasks (" + strings.Join(sqliteColumns, ", ") + ") VALUES (?" + strings.Repeat(", ?", len(sqliteColumns)-1) + ")"
+	sqliteUpdate = "UPDATE tasks SET " + strings.Join(sqliteColumns[1:len(sqliteColumns)-1], " = ?, ") + " = ? WHERE id = ? RETURNING uuid"
+)
+
+func sqliteValues(task Task) []any {
+	var id any // NULL lets AUTOINCREMENT pick the ID
+	if task.ID != 0 {
+		id = task.ID
+	}
+	return []any{
+		id,
+		task.Task,
+		formatSQLiteTime(task.CreatedAt),
+		task.Closed(),
+		formatSQLiteTime(task.Due),
+		task.Priority,
+		strings.Join(task.Tags, " "),
+		task.Project,
+		task.Recur,
+		task.RecurParent,
+		task.Parent,
+		formatIDs(task.Depends),
+		cmp.Or(task.Status, StatusPending),
+		formatStatusChanges(task.StatusChanges),
+		formatSQLiteTime(task.WaitUntil),
+		formatSQLiteTime(task.ModifiedAt),
+		formatSQLiteTime(task.CompletedAt),
+		formatIntervals(task.Intervals),
+		formatAnnotations(task.Annotations),
+		formatSQLiteTime(task.DeletedAt),
+		task.UUID,
+	}
+}
+
+// Zero times are stored as NULL
+func formatSQLiteTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+func parseSQLiteTime(value sql.NullString) (time.Time, error) {
+	if !value.Valid {
+		return time.Time{}, nil
+	}
+	return time.Parse(sqliteTimeLayout, value.String)
+}
+
+func (s *SQLiteStorage) Get(id int) (Task, error) {
+	if err := validateID(id); err != nil {
+		return Task{}, err
+	}
+
+	db, err := s.open()
+	if err != nil {
+		return Task{}, err
+	}
+	defer db.Close()
+
+	task, err := scanSQLiteTask(db.QueryRow(sqliteSelect+" WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, &NotFoundError{ID: id}
+	}
+	return task, err
+}
+
+func (s *SQLiteStorage) List(filter Filter) ([]Task, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	query := sqliteSelect + " WHERE deleted_at IS NULL"
+	switch {
+	case filter.Deleted:
+		query = sqliteSelect + " WHERE deleted_at IS NOT NULL"
+	case !filter.All:
+		query += " AND status NOT IN ('done', 'cancelled') AND NOT (recur <> '' AND recur_parent = 0)"
+	}
+	rows, err := db.Query(query + " ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("error querying tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		task, err := scanSQLiteTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+func (s *SQLiteStorage) Create(task Task) (Task, error) {
+	if task.Task == "" {
+		return Task{}, ErrEmptyTask
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now().UTC()
+	}
+	if task.Status == "" {
+		task.Status = StatusPending
+	}
+	if task.ModifiedAt.IsZero() {
+		task.ModifiedAt = task.CreatedAt
+	}
+	task.ID = 0
+	task.UUID = newUUID()
+
+	db, err := s.open()
+	if err != nil {
+		return Task{}, err
+	}
+	defer db.Close()
+
+	res, err := db.Exec(sqliteInsert, sqliteValues(task)...)
+	if err != nil {
+		return Task{}, fmt.Errorf("error inserting task: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Task{}, fmt.Errorf("error reading task ID: %w", err)
+	}
+	task.ID = int(id)
+	return task, nil
+}
+
+func (s *SQLiteStorage) Update(task Task) (Task, error) {
+	if err := validateID(task.ID); err != nil {
+		return Task{}, err
+	}
+	if task.Task == "" {
+		return Task{}, ErrEmptyTask
+	}
+	task.ModifiedAt = time.Now().UTC()
+
+	db, err := s.open()
+	if err != nil {
+		return Task{}, err
+	}
+	defer db.Close()
+
+	values := sqliteValues(task)
+	err = db.QueryRow(sqliteUpdate, append(values[1:len(values)-1], task.ID)...).Scan(&task.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, &NotFoundError{ID: task.ID}
+	}
+	if err != nil {
+		return Task{}, fmt.Errorf("error updating task: %w", err)
+	}
+	return task, nil
+}
+
+func (s *SQLiteStorage) Delete(id int) error {
+	if err := validateID(id); err != nil {
+		return err
+	}
+
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM tasks WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("error deleting task: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return &NotFoundError{ID: id}
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) Search(query SearchQuery) ([]SearchResult, error) {
+	// Also validates the query before touching the database
+	if _, err := query.matcher(); err != nil {
+		return nil, err
+	}
+
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// The index narrows substring searches down, the trigram tokenizer needs at
+	// least three characters. Everything else goes through every task
+	sqlQuery := sqliteSelect
+	var args []any
+	if cmp.Or(query.Mode, SearchSubstring) == SearchSubstring && utf8.RuneCountInString(query.Text) >= 3 {
+		sqlQuery += " WHERE id IN (SELECT rowid FROM tasks_fts WHERE tasks_fts MATCH ?)"
+		// A quoted string is a phrase, the text is looked up as is
+		args = append(args, `"`+strings.ReplaceAll(query.Text, `"`, `""`)+`"`)
+	}
+	rows, err := db.Query(sqlQuery+" ORDER BY id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error searching tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		task, err := scanSQLiteTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading tasks: %w", err)
+	}
+	return searchTasks(tasks, query)
+}
+
+func (s *SQLiteStorage) Export() (Snapshot, error) {
+	db, err := s.open()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer db.Close()
+	return readSQLiteSnapshot(db)
+}
+
+// Either *sql.DB or *sql.Tx
+type sqliteQueryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Every task along with the last ID handed out
+func readSQLiteSnapshot(db sqliteQueryer) (Snapshot, error) {
+	var snap Snapshot
+	// sqlite_sequence has no row for the table until the first insert
+	err := db.QueryRow("SELECT seq FROM sqlite_sequence WHERE name = 'tasks'").Scan(&snap.LastID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Snapshot{}, fmt.Errorf("error reading last ID: %w", err)
+	}
+
+	rows, err := db.Query(sqliteSelect + " ORDER BY id")
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("error querying tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanSQLiteTask(rows)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		snap.Tasks = append(snap.Tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return Snapshot{}, fmt.Errorf("error reading tasks: %w", err)
+	}
+	return snap, nil
+}
+
+func (s *SQLiteStorage) Replace(snap Snapshot) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op once committed
+
+	if _, err := tx.Exec("DELETE FROM tasks"); err != nil {
+		return fmt.Errorf("error deleting tasks: %w", err)
+	}
+	snap.Tasks = slices.Clone(snap.Tasks)
+	assignUUIDs(snap.Tasks)
+	for _, task := range snap.Tasks {
+		if _, err := tx.Exec(sqliteInsert, sqliteValues(task)...); err != nil {
+			return fmt.Errorf("error inserting task %d: %w", task.ID, err)
+		}
+	}
+
+	if err := raiseSQLiteSequence(tx, snap.LastID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Explicit IDs already move the sequence up to the highest one inserted,
+// lastID may be higher still if the last tasks were deleted
+func raiseSQLiteSequence(tx *sql.Tx, lastID int) error {
+	if lastID <= 0 {
+		return nil
+	}
+	res, err := tx.Exec("UPDATE sqlite_sequence SET seq = max(seq, ?) WHERE name = 'tasks'", lastID)
+	if err != nil {
+		return fmt.Errorf("error updating last ID: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		if _, err := tx.Exec("INSERT INTO sqlite_sequence (name, seq) VALUES ('tasks', ?)", lastID); err != nil {
+			return fmt.Errorf("error updating last ID: %w", err)
+		}
+	}
+	return nil
+}
+
+// Only the tasks fn changed are written, removed first so a UUID can move to
+// another ID
+func (s *SQLiteStorage) Transact(fn func(snap *Snapshot) error) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op once committed
+
+	snap, err := readSQLiteSnapshot(tx)
+	if err != nil {
+		return err
+	}
+	stored := map[int]Task{}
+	for _, task := range snap.Tasks {
+		stored[task.ID] = task.clone()
+	}
+	if err := fn(&snap); err != nil {
+		return err
+	}
+	assignUUIDs(snap.Tasks)
+
+	var changed []Task
+	kept := map[int]bool{}
+	for _, task := range snap.Tasks {
+		kept[task.ID] = true
+		if old, ok := stored[task.ID]; !ok || !old.equal(task) {
+			changed = append(changed, task)
+		}
+	}
+	for id := range stored {
+		if !kept[id] || slices.ContainsFunc(changed, func(task Task) bool { return task.ID == id }) {
+			if _, err := tx.Exec("DELETE FROM tasks WHERE id = ?", id); err != nil {
+				return fmt.Errorf("error deleting task %d: %w", id, err)
+			}
+		}
+	}
+	for _, task := range changed {
+		if _, err := tx.Exec(sqliteInsert, sqliteValues(task)...); err != nil {
+			return fmt.Errorf("error inserting task %d: %w", task.ID, err)
+		}
+	}
+	if err := raiseSQLiteSequence(tx, snap.LastID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) Renumber(reserved []int) (map[int]int, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op once committed
+
+	var snap Snapshot
+	rows, err := tx.Query(sqliteSelect + " ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("error querying tasks: %w", err)
+	}
+	for rows.Next() {
+		task, err := scanSQLiteTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		snap.Tasks = append(snap.Tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading tasks: %w", err)
+	}
+
+	// Dropped references change tasks that keep their ID too
+	ids := renumber(&snap, reserved)
+	if _, err := tx.Exec("DELETE FROM tasks"); err != nil {
+		return nil, fmt.Errorf("error deleting tasks: %w", err)
+	}
+	for _, task := range snap.Tasks {
+		if _, err := tx.Exec(sqliteInsert, sqliteValues(task)...); err != nil {
+			return nil, fmt.Errorf("error inserting task %d: %w", task.ID, err)
+		}
+	}
+	// Unlike Replace, the sequence goes back to the last task, or to the last
+	// reserved ID
+	if _, err := tx.Exec("UPDATE sqlite_sequence SET seq = ? WHERE name = 'tasks'", snap.LastID); err != nil {
+		return nil, fmt.Errorf("error updating last ID: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return ids, nil
+}
+
+// Either *sql.Row or *sql.Rows
+type sqliteScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSQLiteTask(row sqliteScanner) (Task, error) {
+	var (
+		task       Task
+		created    string
+		due        sql.NullString
+		tags       string
+		depends    string
+		isComplete bool
+		changes    string
+		waitUntil  sql.NullString
+		modified   sql.NullString
+		completed  sql.NullString
+		intervals  string
+		notes      string
+		deleted    sql.NullString
+		uuid       sql.NullString
+	)
+	if err := row.Scan(&task.ID, &task.Task, &created, &isComplete, &due, &task.Priority, &tags, &task.Project,
+		&task.Recur, &task.RecurParent, &task.Parent, &depends, &task.Status, &changes, &waitUntil,
+		&modified, &completed, &intervals, &notes, &deleted, &uuid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Task{}, err
+		}
+		return Task{}, fmt.Errorf("error scanning task: %w", err)
+	}
+
+	createdAt, err := time.Parse(sqliteTimeLayout, created)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing created at time: %w", err)
+	}
+	task.CreatedAt = createdAt
+
+	if task.Due, err = parseSQLiteTime(due); err != nil {
+		return Task{}, fmt.Errorf("error parsing due time: %w", err)
+	}
+	task.Tags = NormalizeTags(strings.Fields(tags))
+	if task.Depends, err = parseIDs(depends); err != nil {
+		return Task{}, fmt.Errorf("error parsing dependencies: %w", err)
+	}
+	if task.StatusChanges, err = parseStatusChanges(changes); err != nil {
+		return Task{}, err
+	}
+	if task.WaitUntil, err = parseSQLiteTime(waitUntil); err != nil {
+		return Task{}, fmt.Errorf("error parsing wait until time: %w", err)
+	}
+	if task.ModifiedAt, err = parseSQLiteTime(modified); err != nil {
+		return Task{}, fmt.Errorf("error parsing modified at time: %w", err)
+	}
+	if task.CompletedAt, err = parseSQLiteTime(completed); err != nil {
+		return Task{}, fmt.Errorf("error parsing completed at time: %w", err)
+	}
+	if task.Intervals, err = parseIntervals(intervals); err != nil {
+		return Task{}, err
+	}
+	if task.Annotations, err = parseAnnotations(notes); err != nil {
+		return Task{}, err
+	}
+	if task.DeletedAt, err = parseSQLiteTime(deleted); err != nil {
+		return Task{}, fmt.Errorf("error parsing deleted at time: %w", err)
+	}
+	task.UUID = uuid.String
+
+	return task, nil
+}
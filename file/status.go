@@ -0,0 +1,142 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Where a task is in its workflow. Done and cancelled tasks are closed, every
+// other status is open
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusStarted   Status = "started"
+	StatusWaiting   Status = "waiting"
+	StatusBlocked   Status = "blocked"
+	StatusDone      Status = "done"
+	StatusCancelled Status = "cancelled"
+)
+
+// Every status, in the order they are listed in
+var Statuses = []Status{StatusStarted, StatusPending, StatusBlocked, StatusWaiting, StatusDone, StatusCancelled}
+
+// Accepts the name of a status in any case, and completed for done
+func ParseStatus(s string) (Status, error) {
+	s = strings.ToLower(s)
+	if s == "completed" {
+		return StatusDone, nil
+	}
+	if status := Status(s); slices.Contains(Statuses, status) {
+		return status, nil
+	}
+	return "", fmt.Errorf("invalid status %q, use pending, started, waiting, blocked, done or cancelled", s)
+}
+
+// Status a task was moved to and when
+type StatusChange struct {
+	Status Status
+	At     time.Time
+}
+
+// Move the task to status, recording when it happened (and when it was
+// completed, for done). Time is tracked from the moment a task is started until
+// it is moved to another status. Moving a task to the status it already has
+// changes nothing, except for starting time tracking on a started task that
+// was not being tracked
+func (t *Task) SetStatus(status Status, at time.Time) {
+	if status == StatusStarted {
+		t.startInterval(at)
+	} else {
+		t.stopInterval(at)
+	}
+	if t.Status == status {
+		return
+	}
+	t.Status = status
+	t.StatusChanges = append(t.StatusChanges, StatusChange{Status: status, At: at.UTC()})
+	if status != StatusWaiting {
+		t.WaitUntil = time.Time{}
+	}
+	t.CompletedAt = time.Time{}
+	if status == StatusDone {
+		t.CompletedAt = at.UTC()
+	}
+}
+
+// The status of the task at now: a waiting task is pending again once the time
+// it was waiting for has come. Tasks written before statuses existed have an
+// empty status, which is pending
+func (t Task) StatusAt(now time.Time) Status {
+	switch {
+	case t.Status == "":
+		return StatusPending
+	case t.Status == StatusWaiting && !t.WaitUntil.IsZero() && !t.WaitUntil.After(now):
+		return StatusPending
+	}
+	return t.Status
+}
+
+// Whether the task is done
+func (t Task) IsComplete() bool {
+	return t.Status == StatusDone
+}
+
+// Whether the task is done or cancelled, that is, it needs no more work
+func (t Task) Closed() bool {
+	return t.Status == StatusDone || t.Status == StatusCancelled
+}
+
+// When the task was last moved to its current status, zero if it never was
+func (t Task) StatusSince() time.Time {
+	for _, change := range slices.Backward(t.StatusChanges) {
+		if change.Status == t.Status {
+			return change.At
+		}
+	}
+	return time.Time{}
+}
+
+// Status changes are stored as space separated "status@time" words, like
+// "started@2025-06-15T04:07:04Z done@2025-06-16T10:00:00Z"
+func formatStatusChanges(changes []StatusChange) string {
+	words := make([]string, len(changes))
+	for i, change := range changes {
+		words[i] = string(change.Status) + "@" + change.At.UTC().Format(time.RFC3339Nano)
+	}
+	return strings.Join(words, " ")
+}
+
+func parseStatusChanges(value string) ([]StatusChange, error) {
+	var changes []StatusChange
+	for word := range strings.FieldsSeq(value) {
+		name, at, ok := strings.Cut(word, "@")
+		if !ok {
+			return nil, fmt.Errorf("invalid status change %q", word)
+		}
+		status, err := ParseStatus(name)
+		if err != nil {
+			return nil, err
+		}
+		changedAt, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status change time %q: %w", at, err)
+		}
+		changes = append(changes, StatusChange{Status: status, At: changedAt})
+	}
+	return changes, nil
+}
+
+// Status of a task read from a file written before statuses existed, which
+// only knew whether it was complete
+func legacyStatus(isComplete bool) Status {
+	if isComplete {
+		return StatusDone
+	}
+	return StatusPending
+}
@@ -0,0 +1,105 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTask_SetStatus(t *testing.T) {
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			repo, err := SelectStorage(filepath.Join(dir, "tasks"), storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			task, err := repo.Create(Task{Task: "renew passport"})
+			if err != nil {
+				t.Fatalf("Create() failed: %v", err)
+			}
+			if task.Status != StatusPending {
+				t.Errorf("Create() status = %q, want pending", task.Status)
+			}
+
+			task.SetStatus(StatusStarted, now)
+			task.SetStatus(StatusStarted, now.Add(time.Hour))
+			task.SetStatus(StatusWaiting, now.Add(2*time.Hour))
+			task.WaitUntil = now.AddDate(0, 0, 7)
+			if _, err := repo.Update(task); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+
+			got, err := repo.Get(task.ID)
+			if err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			want := []StatusChange{{StatusStarted, now}, {StatusWaiting, now.Add(2 * time.Hour)}}
+			if !slices.Equal(got.StatusChanges, want) {
+				t.Errorf("StatusChanges = %v, want %v", got.StatusChanges, want)
+			}
+			if !got.WaitUntil.Equal(task.WaitUntil) || !got.StatusSince().Equal(now.Add(2*time.Hour)) {
+				t.Errorf("Get() = %+v, want it waiting until %v since two hours after now", got, task.WaitUntil)
+			}
+			if status := got.StatusAt(now); status != StatusWaiting {
+				t.Errorf("StatusAt(now) = %q, want waiting", status)
+			}
+			if status := got.StatusAt(now.AddDate(0, 0, 8)); status != StatusPending {
+				t.Errorf("StatusAt(next week) = %q, want pending", status)
+			}
+
+			got.SetStatus(StatusDone, now.Add(3*time.Hour))
+			if !got.CompletedAt.Equal(now.Add(3 * time.Hour)) {
+				t.Errorf("SetStatus(done) = %+v, want it completed three hours after now", got)
+			}
+			got.SetStatus(StatusCancelled, now.Add(4*time.Hour))
+			if !got.Closed() || got.IsComplete() || !got.WaitUntil.IsZero() || !got.CompletedAt.IsZero() {
+				t.Errorf("SetStatus(cancelled) = %+v, want a closed task that is neither waiting nor completed", got)
+			}
+		})
+	}
+}
+
+func TestLegacyIsComplete(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"csv": "ID,Task,CreatedAt,IsComplete\n" +
+			"1,do the dishes,\"Sun, 15 Jun 2025 04:07:04 UTC\",true\n" +
+			"2,water the plants,\"Sun, 15 Jun 2025 04:07:04 UTC\",false\n",
+		"json": `[{"ID":1,"Task":"do the dishes","CreatedAt":"2025-06-15T04:07:04Z","IsComplete":true},` +
+			`{"ID":2,"Task":"water the plants","CreatedAt":"2025-06-15T04:07:04Z","IsComplete":false}]`,
+	}
+	for storageType, content := range files {
+		t.Run(storageType, func(t *testing.T) {
+			path := filepath.Join(dir, "tasks")
+			if err := os.WriteFile(path+"."+storageType, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			repo, err := SelectStorage(path, storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tasks, err := repo.List(Filter{All: true})
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			if len(tasks) != 2 || tasks[0].Status != StatusDone || tasks[1].Status != StatusPending {
+				t.Errorf("List() = %+v, want a done and a pending task", tasks)
+			}
+		})
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	for _, s := range []string{"Pending", "started", "WAITING", "blocked", "done", "completed", "cancelled"} {
+		if _, err := ParseStatus(s); err != nil {
+			t.Errorf("ParseStatus(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseStatus("deleted"); err == nil {
+		t.Error("ParseStatus(deleted) succeeded, want an error")
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// Repository on top of a single file that is read and rewritten as a whole. The
+// CSV and JSON storages only differ in how a Snapshot is read and written
+type fileStore struct {
+	filepath string
+	read     func(r io.Reader) (Snapshot, error)
+	write    func(w io.Writer, snap Snapshot) error
+
+	// Search index of the file as it was when indexed, see searchIndex
+	index   *searchIndex
+	indexed os.FileInfo
+}
+
+// Read every task in the file
+func (s *fileStore) load() (Snapshot, error) {
+	file, err := LoadFile(s.filepath)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("error loading file: %w", err)
+	}
+	defer CloseFile(file)
+
+	snap, err := s.read(file)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	// Tasks written before UUIDs existed get theirs the first time they are
+	// read, and keep it. So do tasks that were given the ID of another one
+	repaired := snap.repairIDs()
+	if assignUUIDs(snap.Tasks) || repaired {
+		if err := replaceFile(file, func(w io.Writer) error { return s.write(w, snap) }); err != nil {
+			return Snapshot{}, err
+		}
+	}
+	return snap, nil
+}
+
+// Read the file, let fn modify its contents and atomically write them back.
+// The file stays locked the whole time, so concurrent updates are serialized
+func (s *fileStore) update(fn func(snap *Snapshot) error) error {
+	file, err := LoadFile(s.filepath)
+	if err != nil {
+		return fmt.Errorf("error loading file: %w", err)
+	}
+	defer CloseFile(file)
+
+	snap, err := s.read(file)
+	if err != nil {
+		return err
+	}
+	snap.repairIDs()
+	assignUUIDs(snap.Tasks)
+	if err := fn(&snap); err != nil {
+		return err
+	}
+	// Replaced tasks may not have one yet
+	assignUUIDs(snap.Tasks)
+
+	return replaceFile(file, func(w io.Writer) error {
+		return s.write(w, snap)
+	})
+}
+
+func (s *fileStore) Get(id int) (Task, error) {
+	if err := validateID(id); err != nil {
+		return Task{}, err
+	}
+
+	snap, err := s.load()
+	if err != nil {
+		return Task{}, err
+	}
+
+	i := slices.IndexFunc(snap.Tasks, func(task Task) bool { return task.ID == id })
+	if i < 0 {
+		return Task{}, &NotFoundError{ID: id}
+	}
+	return snap.Tasks[i], nil
+}
+
+func (s *fileStore) List(filter Filter) ([]Task, error) {
+	snap, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []Task
+	for _, task := range snap.Tasks {
+		if filter.Match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (s *fileStore) Create(task Task) (Task, error) {
+	var created Task
+	err := s.update(func(snap *Snapshot) error {
+		var err error
+		created, err = snap.create(task)
+		return err
+	})
+	return created, err
+}
+
+func (s *fileStore) Update(task Task) (Task, error) {
+	var updated Task
+	err := s.update(func(snap *Snapshot) error {
+		var err error
+		updated, err = snap.update(task)
+		return err
+	})
+	return updated, err
+}
+
+func (s *fileStore) Delete(id int) error {
+	return s.update(func(snap *Snapshot) error {
+		_, err := snap.delete(id)
+		return err
+	})
+}
+
+func (s *fileStore) Export() (Snapshot, error) {
+	return s.load()
+}
+
+func (s *fileStore) Replace(snap Snapshot) error {
+	return s.update(func(current *Snapshot) error {
+		// The ID sequence never goes backwards, even when every task is replaced
+		snap.LastID = max(snap.LastID, current.LastID)
+		*current = snap
+		return nil
+	})
+}
+
+func (s *fileStore) Transact(fn func(snap *Snapshot) error) error {
+	return s.update(fn)
+}
+
+func (s *fileStore) Renumber(reserved []int) (map[int]int, error) {
+	var ids map[int]int
+	err := s.update(func(snap *Snapshot) error {
+		ids = renumber(snap, reserved)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (s *fileStore) Search(query SearchQuery) ([]SearchResult, error) {
+	index, err := s.searchIndex()
+	if err != nil {
+		return nil, err
+	}
+	tasks := index.tasks
+	// Regular expressions and fuzzy queries have no trigrams to look up
+	if cmp.Or(query.Mode, SearchSubstring) == SearchSubstring {
+		tasks = index.candidates(query.Text)
+	}
+	return searchTasks(tasks, query)
+}
+
+// The search index of the tasks in the file. It is kept until the file
+// changes, which replaceFile always does with a new file
+func (s *fileStore) searchIndex() (*searchIndex, error) {
+	file, err := LoadFile(s.filepath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading file: %w", err)
+	}
+	defer CloseFile(file)
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error getting file info: %w", err)
+	}
+	if s.index != nil && os.SameFile(info, s.indexed) && info.ModTime().Equal(s.indexed.ModTime()) && info.Size() == s.indexed.Size() {
+		return s.index, nil
+	}
+
+	snap, err := s.read(file)
+	if err != nil {
+		return nil, err
+	}
+	s.index, s.indexed = newSearchIndex(snap.Tasks), info
+	return s.index, nil
+}
@@ -1,22 +1,444 @@
 package file
 
 import (
+	"errors"
 	"fmt"
-	"io"
-	"os"
+	"slices"
 	"strconv"
-	"text/tabwriter"
+	"strings"
 	"time"
-
-	"github.com/mergestat/timediff"
-	"github.com/spf13/viper"
 )
 
 type Task struct {
-	ID         int
-	Task       string
-	CreatedAt  time.Time
-	IsComplete bool
+	// Shown and typed on the command line, tasks renumber can change it
+	ID int
+	// Never changes, so it tells a task apart from any other one wherever it
+	// ends up, set by Create
+	UUID      string `json:",omitempty"`
+	Task      string
+	CreatedAt time.Time
+	// Last time the task was stored, set by Create and Update
+	ModifiedAt time.Time `json:",omitzero"`
+	// Zero when the task has no due date
+	Due    time.Time `json:",omitzero"`
+	Status Status
+	// Every status the task was moved to after it was created, see SetStatus
+	StatusChanges []StatusChange `json:",omitempty"`
+	// When a waiting task becomes pending again, zero to wait until told otherwise
+	WaitUntil time.Time `json:",omitzero"`
+	// When the task was done, zero when it is not done
+	CompletedAt time.Time `json:",omitzero"`
+	// Time spent working on the task, oldest first. Only the last one can be
+	// running, and only while the task is started
+	Intervals []Interval `json:",omitempty"`
+	// Notes added to the task, oldest first
+	Annotations []Annotation `json:",omitempty"`
+	// When the task was moved to the trash, zero when it is not there. Deleted
+	// tasks are left out of everything but the trash until they are restored or
+	// purged
+	DeletedAt time.Time `json:",omitzero"`
+	Priority  Priority  `json:",omitempty"`
+	// Sorted and without duplicates, see NormalizeTags
+	Tags []string `json:",omitempty"`
+	// Dot separated hierarchy, like "work.backend"
+	Project string `json:",omitempty"`
+	// Recurrence of a recurring task, see ParseRecurrence. Set on the template
+	// and copied to its instances
+	Recur string `json:",omitempty"`
+	// ID of the template of a recurring task instance, see IsTemplate
+	RecurParent int `json:",omitempty"`
+	// ID of the task this one is a subtask of, zero for top level tasks
+	Parent int `json:",omitempty"`
+	// IDs of the tasks that have to be completed before this one, sorted and
+	// without duplicates, see NormalizeIDs
+	Depends []int `json:",omitempty"`
+}
+
+// Tags and projects are single words, so they can be written as "+tag" and
+// "project:name" and stored space separated
+func ValidateTag(tag string) error {
+	if tag == "" || strings.ContainsAny(tag, " \t\n,") || strings.HasPrefix(tag, "+") || strings.HasPrefix(tag, "-") {
+		return fmt.Errorf("invalid tag %q, tags are single words that do not start with + or -", tag)
+	}
+	return nil
+}
+
+func ValidateProject(project string) error {
+	if strings.ContainsAny(project, " \t\n,") {
+		return fmt.Errorf("invalid project %q, projects are single words like work.backend", project)
+	}
+	return nil
+}
+
+// Sort tags and drop duplicates, nil when there are none
+func NormalizeTags(tags []string) []string {
+	tags = slices.Compact(slices.Sorted(slices.Values(tags)))
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// Sort IDs and drop duplicates, nil when there are none
+func NormalizeIDs(ids []int) []int {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	if len(ids) == 0 {
+		return nil
+	}
+	return ids
+}
+
+// Whether the task is in the trash, see DeletedAt
+func (t Task) Deleted() bool {
+	return !t.DeletedAt.IsZero()
+}
+
+func (t Task) HasTag(tag string) bool {
+	return slices.Contains(t.Tags, tag)
+}
+
+// Whether the task belongs to project or to one of its subprojects
+func (t Task) InProject(project string) bool {
+	return t.Project == project || strings.HasPrefix(t.Project, project+".")
+}
+
+// H, M, L or empty when the task has no priority
+type Priority string
+
+const (
+	PriorityNone   Priority = ""
+	PriorityLow    Priority = "L"
+	PriorityMedium Priority = "M"
+	PriorityHigh   Priority = "H"
+)
+
+// Accepts H/M/L, high/medium/low (in any case) and none
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(s) {
+	case "", "none":
+		return PriorityNone, nil
+	case "l", "low":
+		return PriorityLow, nil
+	case "m", "medium":
+		return PriorityMedium, nil
+	case "h", "high":
+		return PriorityHigh, nil
+	default:
+		return PriorityNone, fmt.Errorf("invalid priority %q, use H, M, L or none", s)
+	}
+}
+
+// Coefficients of each urgency term, loosely based on taskwarrior's defaults
+const (
+	urgencyStarted        = 4.0
+	urgencyWaiting        = -3.0
+	urgencyBlocked        = -5.0
+	urgencyPriorityHigh   = 6.0
+	urgencyPriorityMedium = 3.9
+	urgencyPriorityLow    = 1.8
+	urgencyDue            = 12.0
+	urgencyAge            = 2.0
+	urgencyTags           = 1.0
+)
+
+// How much a task needs attention, higher is more urgent. It grows with the
+// priority, the closeness (or lateness) of the due date, the age of the task
+// and its number of tags. Started tasks need more attention, waiting and
+// blocked ones less. Closed tasks and recurring task templates need no
+// attention at all
+func (t Task) Urgency(now time.Time) float64 {
+	if t.Closed() || t.IsTemplate() {
+		return 0
+	}
+
+	var urgency float64
+
+	switch t.StatusAt(now) {
+	case StatusStarted:
+		urgency += urgencyStarted
+	case StatusWaiting:
+		urgency += urgencyWaiting
+	case StatusBlocked:
+		urgency += urgencyBlocked
+	}
+
+	switch t.Priority {
+	case PriorityHigh:
+		urgency += urgencyPriorityHigh
+	case PriorityMedium:
+		urgency += urgencyPriorityMedium
+	case PriorityLow:
+		urgency += urgencyPriorityLow
+	}
+
+	// Scales from 0.2 two weeks before the due date up to 1 a week after it
+	if !t.Due.IsZero() {
+		days := t.Due.Sub(now).Hours() / 24
+		switch {
+		case days >= 14:
+			urgency += urgencyDue * 0.2
+		case days <= -7:
+			urgency += urgencyDue
+		default:
+			urgency += urgencyDue * ((14-days)*0.8/21 + 0.2)
+		}
+	}
+
+	// Reaches its maximum after a year
+	ageDays := now.Sub(t.CreatedAt).Hours() / 24
+	urgency += urgencyAge * min(max(ageDays/365, 0), 1)
+
+	// Tagged tasks are a bit more important, the more tags the better
+	switch len(t.Tags) {
+	case 0:
+	case 1:
+		urgency += urgencyTags * 0.8
+	case 2:
+		urgency += urgencyTags * 0.9
+	default:
+		urgency += urgencyTags
+	}
+
+	return urgency
+}
+
+// An open task whose due date has passed
+func (t Task) Overdue(now time.Time) bool {
+	return !t.Closed() && !t.Due.IsZero() && t.Due.Before(now)
+}
+
+var (
+	// Returned (wrapped in a NotFoundError) when no task has the requested ID
+	ErrNotFound = errors.New("task not found")
+	// Returned when a task ID is not a positive integer
+	ErrInvalidID = errors.New("task ID must be greater than 0")
+	// Returned when creating or updating a task without a description
+	ErrEmptyTask = errors.New("task description is empty")
+)
+
+type NotFoundError struct {
+	ID int
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("task with ID %d not found", e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func validateID(id int) error {
+	if id <= 0 {
+		return fmt.Errorf("%w, got %d", ErrInvalidID, id)
+	}
+	return nil
+}
+
+// Selects which tasks Repository.List returns, the zero value lists the open
+// tasks that are not waiting
+type Filter struct {
+	// Include closed and waiting tasks, and the templates of recurring tasks
+	All bool
+	// Only tasks with every one of these tags
+	Tags []string
+	// Only tasks with none of these tags
+	ExcludeTags []string
+	// Only tasks in this project or its subprojects
+	Project string
+	// Only tasks this returns true for, like a parsed filter expression
+	Where func(task Task) bool
+	// Only the deleted tasks, whatever their status, instead of leaving them out
+	Deleted bool
+}
+
+func (f Filter) Match(task Task) bool {
+	if task.Deleted() != f.Deleted {
+		return false
+	}
+	if !f.All && !f.Deleted && (task.Closed() || task.IsTemplate() || task.StatusAt(time.Now()) == StatusWaiting) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !task.HasTag(tag) {
+			return false
+		}
+	}
+	for _, tag := range f.ExcludeTags {
+		if task.HasTag(tag) {
+			return false
+		}
+	}
+	if f.Project != "" && !task.InProject(f.Project) {
+		return false
+	}
+	return f.Where == nil || f.Where(task)
+}
+
+// A copy of the task that shares none of its lists
+func (t Task) clone() Task {
+	t.StatusChanges = slices.Clone(t.StatusChanges)
+	t.Intervals = slices.Clone(t.Intervals)
+	t.Annotations = slices.Clone(t.Annotations)
+	t.Tags = slices.Clone(t.Tags)
+	t.Depends = slices.Clone(t.Depends)
+	return t
+}
+
+// Whether two versions of a task hold the same values. Times are the same when
+// they are the same instant, to the nanosecond, and an empty list is the same
+// as a missing one
+func (t Task) equal(other Task) bool {
+	return t.ID == other.ID && t.UUID == other.UUID && t.Task == other.Task &&
+		t.CreatedAt.Equal(other.CreatedAt) && t.ModifiedAt.Equal(other.ModifiedAt) && t.Due.Equal(other.Due) &&
+		t.Status == other.Status &&
+		slices.EqualFunc(t.StatusChanges, other.StatusChanges, func(a, b StatusChange) bool {
+			return a.Status == b.Status && a.At.Equal(b.At)
+		}) &&
+		t.WaitUntil.Equal(other.WaitUntil) && t.CompletedAt.Equal(other.CompletedAt) &&
+		slices.EqualFunc(t.Intervals, other.Intervals, func(a, b Interval) bool {
+			return a.Start.Equal(b.Start) && a.End.Equal(b.End)
+		}) &&
+		slices.EqualFunc(t.Annotations, other.Annotations, func(a, b Annotation) bool {
+			return a.At.Equal(b.At) && a.Text == b.Text
+		}) &&
+		t.DeletedAt.Equal(other.DeletedAt) && t.Priority == other.Priority &&
+		slices.Equal(t.Tags, other.Tags) && t.Project == other.Project &&
+		t.Recur == other.Recur && t.RecurParent == other.RecurParent &&
+		t.Parent == other.Parent && slices.Equal(t.Depends, other.Depends)
+}
+
+// Point the references to other tasks at their new IDs, references to tasks
+// that are not in ids are left as they are
+func (t *Task) remapIDs(ids map[int]int) {
+	if id, ok := ids[t.RecurParent]; ok && t.RecurParent != 0 {
+		t.RecurParent = id
+	}
+	if id, ok := ids[t.Parent]; ok && t.Parent != 0 {
+		t.Parent = id
+	}
+	for i, dep := range t.Depends {
+		if id, ok := ids[dep]; ok {
+			t.Depends[i] = id
+		}
+	}
+	t.Depends = NormalizeIDs(t.Depends)
+}
+
+// Every task in a store along with its ID high-water mark. It is also the
+// in-memory form of a CSV or JSON tasks file
+type Snapshot struct {
+	// Highest ID ever handed out. It only grows, so the ID of a deleted task is
+	// never given to another one
+	LastID int
+	Tasks  []Task
+}
+
+// Reserve the next task ID. Files written before LastID existed fall back to
+// the highest ID in use
+func (s *Snapshot) nextID() int {
+	for _, task := range s.Tasks {
+		s.LastID = max(s.LastID, task.ID)
+	}
+	s.LastID++
+	return s.LastID
+}
+
+// Give the tasks that share their ID with an earlier one new IDs after the last,
+// so each can be reached by its ID. Older versions gave a new task the ID of
+// the last one. References to a shared ID keep pointing at the first task.
+// Returns whether any task was given a new ID
+func (s *Snapshot) repairIDs() bool {
+	seen := map[int]bool{}
+	var duplicates []int
+	for i, task := range s.Tasks {
+		if seen[task.ID] {
+			duplicates = append(duplicates, i)
+		}
+		seen[task.ID] = true
+	}
+	for _, i := range duplicates {
+		s.Tasks[i].ID = s.nextID()
+	}
+	return len(duplicates) > 0
+}
+
+// Add task as a new one with the next ID, see Repository.Create
+func (s *Snapshot) create(task Task) (Task, error) {
+	if task.Task == "" {
+		return Task{}, ErrEmptyTask
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now().UTC()
+	}
+	if task.Status == "" {
+		task.Status = StatusPending
+	}
+	if task.ModifiedAt.IsZero() {
+		task.ModifiedAt = task.CreatedAt
+	}
+	task.ID = s.nextID()
+	task.UUID = newUUID()
+	s.Tasks = append(s.Tasks, task)
+	return task, nil
+}
+
+// Overwrite the task with the same ID, see Repository.Update
+func (s *Snapshot) update(task Task) (Task, error) {
+	if err := validateID(task.ID); err != nil {
+		return Task{}, err
+	}
+	if task.Task == "" {
+		return Task{}, ErrEmptyTask
+	}
+	i := slices.IndexFunc(s.Tasks, func(t Task) bool { return t.ID == task.ID })
+	if i < 0 {
+		return Task{}, &NotFoundError{ID: task.ID}
+	}
+	task.ModifiedAt = time.Now().UTC()
+	task.UUID = s.Tasks[i].UUID
+	s.Tasks[i] = task
+	return task, nil
+}
+
+// Change task id with fn and store it, as a single change to repo (see
+// Repository.Transact), so nothing changed on the task in the meantime is lost.
+// fn gets the task as it is stored and can refuse the change with an error.
+// Returns the task as stored
+func UpdateTask(repo Repository, id int, fn func(task *Task) error) (Task, error) {
+	var updated Task
+	err := repo.Transact(func(snap *Snapshot) error {
+		i := slices.IndexFunc(snap.Tasks, func(task Task) bool { return task.ID == id })
+		if i < 0 {
+			return &NotFoundError{ID: id}
+		}
+		task := snap.Tasks[i].clone()
+		if err := fn(&task); err != nil {
+			return err
+		}
+		var err error
+		updated, err = snap.update(task)
+		return err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return updated, nil
+}
+
+// Remove the task with the given ID, see Repository.Delete. Returns it as it
+// was
+func (s *Snapshot) delete(id int) (Task, error) {
+	if err := validateID(id); err != nil {
+		return Task{}, err
+	}
+	i := slices.IndexFunc(s.Tasks, func(task Task) bool { return task.ID == id })
+	if i < 0 {
+		return Task{}, &NotFoundError{ID: id}
+	}
+	task := s.Tasks[i]
+	s.Tasks = slices.Delete(s.Tasks, i, i+1)
+	return task, nil
 }
 
 func newTask(taskID, task, created, isComplete string) (Task, error) {
@@ -45,46 +467,9 @@ func newTask(taskID, task, created, isComplete string) (Task, error) {
 	}
 
 	return Task{
-		ID:         id,
-		Task:       task,
-		CreatedAt:  createdAt,
-		IsComplete: isComplete == "true", // any other value than "true" will default to "false"
+		ID:        id,
+		Task:      task,
+		CreatedAt: createdAt,
+		Status:    legacyStatus(isComplete == "true"), // any other value than "true" will default to "false"
 	}, nil
 }
-
-func printTasks(w io.Writer, tasks []Task) {
-	printAll := viper.GetBool("all")
-	tabW := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
-
-	// TODO: perhaps it's better to return the error here?
-	_, err := tabW.Write(fmt.Appendf(nil, "ID\t Task\t Created\t Done\n"))
-	if err != nil {
-		fmt.Fprintf(os.Stdout, "Could not write header to stdout: %v\n", err)
-		return
-	}
-
-	for _, task := range tasks {
-		formattedCreatedAt := timediff.TimeDiff(task.CreatedAt)
-
-		if !task.IsComplete {
-			_, err := tabW.Write(fmt.Appendf(nil, "%d\t %s\t %s\t %t\n", task.ID, task.Task, formattedCreatedAt, task.IsComplete))
-			if err != nil {
-				fmt.Fprintf(os.Stdout, "Could not write to stdout: %v\n", err)
-				return
-			}
-			continue
-		}
-		if printAll {
-			_, err := tabW.Write(fmt.Appendf(nil, "%d\t %s\t %s\t %t\n", task.ID, task.Task, formattedCreatedAt, task.IsComplete))
-			if err != nil {
-				fmt.Fprintf(os.Stdout, "Could not write to stdout: %v\n", err)
-				return
-			}
-		}
-	}
-
-	if err := tabW.Flush(); err != nil {
-		fmt.Fprintf(os.Stderr, "Could not print tasks: %v\n", err)
-		return
-	}
-}
@@ -1,6 +1,11 @@
 package file
 
 import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -17,10 +22,10 @@ func Test_newTask(t *testing.T) {
 			name:   "valid task",
 			record: []string{"0", "do the dishes", "Sun, 15 Jun 2025 04:07:04 UTC", "true"},
 			want: Task{
-				ID:         0,
-				Task:       "do the dishes",
-				CreatedAt:  time.Date(2025, 6, 15, 4, 7, 4, 0, time.UTC),
-				IsComplete: true,
+				ID:        0,
+				Task:      "do the dishes",
+				CreatedAt: time.Date(2025, 6, 15, 4, 7, 4, 0, time.UTC),
+				Status:    StatusDone,
 			},
 		},
 		{
@@ -37,10 +42,10 @@ func Test_newTask(t *testing.T) {
 			name:   "invalid isComplete value defaults to false",
 			record: []string{"0", "do the dishes", "Sun, 15 Jun 2025 04:07:04 UTC", "42"},
 			want: Task{
-				ID:         0,
-				Task:       "do the dishes",
-				CreatedAt:  time.Date(2025, 6, 15, 4, 7, 4, 0, time.UTC),
-				IsComplete: false,
+				ID:        0,
+				Task:      "do the dishes",
+				CreatedAt: time.Date(2025, 6, 15, 4, 7, 4, 0, time.UTC),
+				Status:    StatusPending,
 			},
 		},
 	}
@@ -56,9 +61,132 @@ func Test_newTask(t *testing.T) {
 			if tt.wantErr {
 				t.Fatal("newTask() succeeded unexpectedly")
 			}
-			if tt.want != got {
+			if !reflect.DeepEqual(tt.want, got) {
 				t.Errorf("newTask() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_taskFile_nextID(t *testing.T) {
+	tests := []struct {
+		name string
+		snap Snapshot
+		want int
+	}{
+		{name: "empty file", snap: Snapshot{}, want: 1},
+		{name: "deleted tasks are not reused", snap: Snapshot{LastID: 5, Tasks: []Task{{ID: 2}}}, want: 6},
+		{name: "legacy file without LastID", snap: Snapshot{Tasks: []Task{{ID: 4}, {ID: 2}}}, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.snap.nextID(); got != tt.want {
+				t.Errorf("nextID() = %d, want %d", got, tt.want)
+			}
+			if tt.snap.LastID != tt.want {
+				t.Errorf("LastID = %d, want %d", tt.snap.LastID, tt.want)
+			}
+		})
+	}
+}
+
+// Files written by older versions, which gave a new task the ID of the last
+// one, have tasks sharing an ID. Each gets an ID of its own when read
+func TestDuplicateIDsOfOlderFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks")
+	data := `[{"ID":1,"Task":"first","CreatedAt":"2025-06-18T10:30:00Z","IsComplete":false},` +
+		`{"ID":1,"Task":"second","CreatedAt":"2025-06-18T10:31:00Z","IsComplete":false},` +
+		`{"ID":1,"Task":"third","CreatedAt":"2025-06-18T10:32:00Z","IsComplete":false},` +
+		`{"ID":2,"Task":"fourth","CreatedAt":"2025-06-18T10:33:00Z","IsComplete":false}]`
+	if err := os.WriteFile(path+".json", []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := SelectStorage(path, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[int]string{1: "first", 2: "fourth", 3: "second", 4: "third"} {
+		if got, err := store.Get(id); err != nil || got.Task != want {
+			t.Errorf("Get(%d) = %+v, %v, want task %q", id, got, err, want)
+		}
+	}
+	// The second task can be changed on its own
+	second, err := store.Get(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Status = StatusDone
+	if _, err := store.Update(second); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if first, _ := store.Get(1); first.Status != StatusPending {
+		t.Errorf("Get(1) after updating task 3 = %+v, want it pending", first)
+	}
+	// The new IDs are kept in the file
+	saved, err := os.ReadFile(path + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(saved), `"ID":1,`) != 1 {
+		t.Errorf("file after repair = %s, want a single task 1", saved)
+	}
+	if created, err := store.Create(Task{Task: "fifth"}); err != nil || created.ID != 5 {
+		t.Errorf("Create() = %+v, %v, want ID 5", created, err)
+	}
+}
+
+func TestTask_equal(t *testing.T) {
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	task := Task{
+		ID: 1, UUID: "3f2a9c1e-8b4d-4e6f-a1b2-c3d4e5f60718", Task: "Fix login", CreatedAt: now,
+		Status: StatusStarted, StatusChanges: []StatusChange{{Status: StatusStarted, At: now}},
+		Intervals: []Interval{{Start: now}}, Tags: []string{"bug"},
+	}
+	tests := []struct {
+		name   string
+		change func(t *Task)
+		want   bool
+	}{
+		{name: "same task", change: func(t *Task) {}, want: true},
+		{name: "same instant elsewhere", change: func(t *Task) { t.CreatedAt = now.In(time.FixedZone("CEST", 2*60*60)) }, want: true},
+		{name: "empty list", change: func(t *Task) { t.Depends = []int{} }, want: true},
+		{name: "below a second", change: func(t *Task) { t.ModifiedAt = now.Add(time.Millisecond) }, want: false},
+		{name: "interval stopped", change: func(t *Task) { t.Intervals = []Interval{{Start: now, End: now.Add(time.Minute)}} }, want: false},
+		{name: "annotated", change: func(t *Task) { t.Annotations = []Annotation{{At: now, Text: "call back"}} }, want: false},
+		{name: "tag", change: func(t *Task) { t.Tags = []string{"urgent"} }, want: false},
+		{name: "parent", change: func(t *Task) { t.Parent = 2 }, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := task.clone()
+			tt.change(&other)
+			if got := task.equal(other); got != tt.want {
+				t.Errorf("equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTask_Urgency(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		task Task
+		want float64
+	}{
+		{name: "new task", task: Task{CreatedAt: now}, want: 0},
+		{name: "high priority", task: Task{CreatedAt: now, Priority: PriorityHigh}, want: 6},
+		{name: "due far away", task: Task{CreatedAt: now, Due: now.AddDate(0, 1, 0)}, want: 2.4},
+		{name: "due now", task: Task{CreatedAt: now, Due: now}, want: 12 * (14*0.8/21 + 0.2)},
+		{name: "long overdue", task: Task{CreatedAt: now, Due: now.AddDate(0, 0, -10)}, want: 12},
+		{name: "a year old", task: Task{CreatedAt: now.AddDate(-2, 0, 0), Priority: PriorityLow}, want: 3.8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.task.Urgency(now); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Urgency() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A stretch of time spent working on a task. End is zero while the task is
+// being worked on
+type Interval struct {
+	Start time.Time
+	End   time.Time `json:",omitzero"`
+}
+
+// How long the interval lasted, up to now when it is still running
+func (i Interval) Duration(now time.Time) time.Duration {
+	if i.End.IsZero() {
+		return now.Sub(i.Start)
+	}
+	return i.End.Sub(i.Start)
+}
+
+// Whether time is being tracked on the task, that is, its last interval is
+// still running
+func (t Task) Active() bool {
+	return len(t.Intervals) > 0 && t.Intervals[len(t.Intervals)-1].End.IsZero()
+}
+
+// Start tracking time at at, nothing changes if it already is
+func (t *Task) startInterval(at time.Time) {
+	if !t.Active() {
+		t.Intervals = append(t.Intervals, Interval{Start: at.UTC()})
+	}
+}
+
+// Stop tracking time at at, nothing changes if it is not being tracked
+func (t *Task) stopInterval(at time.Time) {
+	if t.Active() {
+		t.Intervals[len(t.Intervals)-1].End = at.UTC()
+	}
+}
+
+// Total time tracked on the task, counting a running interval up to now
+func (t Task) TimeSpent(now time.Time) time.Duration {
+	var spent time.Duration
+	for _, interval := range t.Intervals {
+		spent += interval.Duration(now)
+	}
+	return spent
+}
+
+// Intervals are stored as space separated ISO 8601 intervals, like
+// "2025-06-15T04:07:04Z/2025-06-15T05:00:00Z", with nothing after the slash
+// while the interval is running
+func formatIntervals(intervals []Interval) string {
+	words := make([]string, len(intervals))
+	for i, interval := range intervals {
+		words[i] = interval.Start.UTC().Format(time.RFC3339Nano) + "/"
+		if !interval.End.IsZero() {
+			words[i] += interval.End.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func parseIntervals(value string) ([]Interval, error) {
+	var intervals []Interval
+	for word := range strings.FieldsSeq(value) {
+		start, end, ok := strings.Cut(word, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid interval %q", word)
+		}
+		var interval Interval
+		var err error
+		if interval.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return nil, fmt.Errorf("invalid interval start %q: %w", start, err)
+		}
+		if end != "" {
+			if interval.End, err = time.Parse(time.RFC3339, end); err != nil {
+				return nil, fmt.Errorf("invalid interval end %q: %w", end, err)
+			}
+		}
+		intervals = append(intervals, interval)
+	}
+	return intervals, nil
+}
@@ -0,0 +1,49 @@
+package file
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTask_Intervals(t *testing.T) {
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			repo, err := SelectStorage(filepath.Join(dir, "tasks"), storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			task, err := repo.Create(Task{Task: "write report"})
+			if err != nil {
+				t.Fatalf("Create() failed: %v", err)
+			}
+
+			task.SetStatus(StatusStarted, now)
+			task.SetStatus(StatusPending, now.Add(time.Hour))
+			task.SetStatus(StatusStarted, now.Add(2*time.Hour))
+			if _, err := repo.Update(task); err != nil {
+				t.Fatalf("Update() failed: %v", err)
+			}
+			got, err := repo.Get(task.ID)
+			if err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			want := []Interval{{now, now.Add(time.Hour)}, {Start: now.Add(2 * time.Hour)}}
+			if !slices.Equal(got.Intervals, want) || !got.Active() {
+				t.Errorf("Intervals = %v, want %v with the last one running", got.Intervals, want)
+			}
+			if spent := got.TimeSpent(now.Add(150 * time.Minute)); spent != 90*time.Minute {
+				t.Errorf("TimeSpent() = %v, want 1h30m", spent)
+			}
+
+			// Completing a started task stops the clock
+			got.SetStatus(StatusDone, now.Add(3*time.Hour))
+			if got.Active() || got.TimeSpent(now.AddDate(0, 0, 1)) != 2*time.Hour {
+				t.Errorf("SetStatus(done) = %+v, want it stopped after two hours", got)
+			}
+		})
+	}
+}
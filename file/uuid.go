@@ -0,0 +1,112 @@
+/*
+Copyright © 2025 Oscar Marquez
+*/
+package file
+
+import (
+	"crypto/rand"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Shortest UUID prefix taken in place of a task ID, as long as the first group
+const MinUUIDPrefix = 8
+
+// A random (version 4) UUID, like "3f2a9c1e-8b4d-4e6f-a1b2-c3d4e5f60718"
+func newUUID() string {
+	var b [16]byte
+	// Never fails, see crypto/rand.Read
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Give a UUID to the tasks without one, written before UUIDs existed. Returns
+// whether any task was given one
+func assignUUIDs(tasks []Task) bool {
+	assigned := false
+	for i := range tasks {
+		if tasks[i].UUID == "" {
+			tasks[i].UUID = newUUID()
+			assigned = true
+		}
+	}
+	return assigned
+}
+
+// Whether s can be the start of a UUID long enough to stand for a task ID
+func IsUUIDPrefix(s string) bool {
+	if len(s) < MinUUIDPrefix {
+		return false
+	}
+	return !strings.ContainsFunc(s, func(r rune) bool {
+		return !strings.ContainsRune("0123456789abcdefABCDEF-", r)
+	})
+}
+
+// The task whose UUID starts with prefix, deleted ones included. Fails when
+// there is none or more than one
+func FindByUUID(repo Repository, prefix string) (Task, error) {
+	snap, err := repo.Export()
+	if err != nil {
+		return Task{}, err
+	}
+	prefix = strings.ToLower(prefix)
+	var found []Task
+	for _, task := range snap.Tasks {
+		if prefix != "" && strings.HasPrefix(task.UUID, prefix) {
+			found = append(found, task)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Task{}, fmt.Errorf("no task with UUID %s: %w", prefix, ErrNotFound)
+	case 1:
+		return found[0], nil
+	}
+	ids := make([]string, len(found))
+	for i, task := range found {
+		ids[i] = strconv.Itoa(task.ID)
+	}
+	return Task{}, fmt.Errorf("UUID %s is the start of tasks %s, give more of it", prefix, strings.Join(ids, ", "))
+}
+
+// Give the tasks of snap consecutive IDs from 1, in their current order,
+// skipping the reserved ones, and point every reference at the new IDs.
+// LastID restarts from the last task, or from the highest reserved ID when it
+// is higher. Returns the old ID to new ID of every task
+func renumber(snap *Snapshot, reserved []int) map[int]int {
+	slices.SortFunc(snap.Tasks, func(a, b Task) int { return a.ID - b.ID })
+	ids := map[int]int{}
+	next := 0
+	for i := range snap.Tasks {
+		next++
+		for slices.Contains(reserved, next) {
+			next++
+		}
+		ids[snap.Tasks[i].ID] = next
+	}
+	for i := range snap.Tasks {
+		snap.Tasks[i].ID = ids[snap.Tasks[i].ID]
+		snap.Tasks[i].renumber(ids)
+	}
+	snap.LastID = next
+	for _, id := range reserved {
+		snap.LastID = max(snap.LastID, id)
+	}
+	return ids
+}
+
+// Point the references to other tasks at their new IDs after renumbering.
+// References to tasks that are not in ids (purged or archived ones) are
+// dropped, they would otherwise point at whatever task takes their ID
+func (t *Task) renumber(ids map[int]int) {
+	t.dropReferences(func(id int) bool {
+		_, ok := ids[id]
+		return !ok
+	})
+	t.remapIDs(ids)
+}
@@ -0,0 +1,169 @@
+package file
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRenumber(t *testing.T) {
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			path := filepath.Join(dir, "tasks")
+			store, err := SelectStorage(path, storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			journal := NewJournal(JournalPath(path, storageType))
+			repo := func(command string) Repository { return journal.Record(store, command) }
+
+			for _, task := range []Task{
+				{Task: "plan the trip"},
+				{Task: "gone"},
+				{Task: "book the hotel", Parent: 1, Depends: []int{2}},
+				{Task: "also gone"},
+				{Task: "pack", Depends: []int{3}},
+			} {
+				if _, err := repo("add").Create(task); err != nil {
+					t.Fatalf("Create() failed: %v", err)
+				}
+			}
+			hotel, err := store.Get(3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hotel.UUID) != 36 {
+				t.Errorf("Create() gave UUID %q, want a UUID", hotel.UUID)
+			}
+			hotel.UUID = "changed"
+			if hotel, err = repo("modify").Update(hotel); err != nil || hotel.UUID == "changed" {
+				t.Errorf("Update() = %+v, %v, want the UUID kept", hotel, err)
+			}
+			for _, id := range []int{2, 4} {
+				if err := repo("delete").Delete(id); err != nil {
+					t.Fatalf("Delete() failed: %v", err)
+				}
+			}
+
+			ids, err := repo("renumber").Renumber(nil)
+			if err != nil {
+				t.Fatalf("Renumber() failed: %v", err)
+			}
+			if want := map[int]int{1: 1, 3: 2, 5: 3}; !maps.Equal(ids, want) {
+				t.Errorf("Renumber() = %v, want %v", ids, want)
+			}
+			renumbered, err := store.Get(2)
+			if err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			// The dependency on a task that is gone is dropped
+			if renumbered.UUID != hotel.UUID || renumbered.Parent != 1 || len(renumbered.Depends) != 0 {
+				t.Errorf("Get() after Renumber() = %+v, want task 3 with its UUID and parent", renumbered)
+			}
+			if pack, _ := store.Get(3); !slices.Equal(pack.Depends, []int{2}) {
+				t.Errorf("Get() after Renumber() = %+v, want it to depend on task 2", pack)
+			}
+			if created, err := store.Create(Task{Task: "new"}); err != nil || created.ID != 4 {
+				t.Errorf("Create() after Renumber() = %+v, %v, want ID 4", created, err)
+			}
+			if again, err := store.Renumber(nil); err != nil || !maps.Equal(again, map[int]int{1: 1, 2: 2, 3: 3, 4: 4}) {
+				t.Errorf("Renumber() again = %v, %v, want nothing changed", again, err)
+			}
+
+			found, err := FindByUUID(store, strings.ToUpper(hotel.UUID[:MinUUIDPrefix]))
+			if err != nil || found.ID != 2 {
+				t.Errorf("FindByUUID() = %+v, %v, want task 2", found, err)
+			}
+			if _, err := FindByUUID(store, "00000000"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("FindByUUID() of an unknown UUID = %v, want ErrNotFound", err)
+			}
+
+			// The history follows the new IDs. Task 4 was given to another task
+			// since, so the deleted task comes back as task 5
+			if _, err := journal.Undo(store, 1, false); err != nil {
+				t.Fatalf("Undo() of the deletion failed: %v", err)
+			}
+			if back, err := store.Get(5); err != nil || back.Task != "also gone" {
+				t.Errorf("Get() after Undo() = %+v, %v, want the deleted task", back, err)
+			}
+			if other, _ := store.Get(4); other.Task != "new" {
+				t.Errorf("Get() after Undo() = %+v, want the new task untouched", other)
+			}
+			// Changes to renumbered tasks are undone on their new IDs, the
+			// dependency on the deleted task was dropped from them too
+			if _, err := journal.Undo(store, 2, false); err != nil {
+				t.Fatalf("Undo() failed: %v", err)
+			}
+			if got, _ := store.Get(2); got.Task != "book the hotel" || got.Parent != 1 {
+				t.Errorf("Get() after Undo() = %+v, want the task with its parent", got)
+			}
+			if _, err := journal.Undo(store, 1, false); err != nil {
+				t.Fatalf("Undo() of the creation of task 5 failed: %v", err)
+			}
+			if _, err := store.Get(3); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() after undoing its creation = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+// The IDs of archived tasks are skipped and the next task is numbered after
+// them, so archiving it never meets another task with its ID
+func TestRenumberReserved(t *testing.T) {
+	dir := t.TempDir()
+	for _, storageType := range []string{"csv", "json", "sqlite"} {
+		t.Run(storageType, func(t *testing.T) {
+			store, err := SelectStorage(filepath.Join(dir, "tasks"), storageType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"one", "two", "three"} {
+				if _, err := store.Create(Task{Task: name}); err != nil {
+					t.Fatalf("Create() failed: %v", err)
+				}
+			}
+			if err := store.Delete(1); err != nil {
+				t.Fatalf("Delete() failed: %v", err)
+			}
+
+			ids, err := store.Renumber([]int{1, 3, 5})
+			if err != nil {
+				t.Fatalf("Renumber() failed: %v", err)
+			}
+			if want := map[int]int{2: 2, 3: 4}; !maps.Equal(ids, want) {
+				t.Errorf("Renumber() = %v, want %v", ids, want)
+			}
+			if created, err := store.Create(Task{Task: "fresh"}); err != nil || created.ID != 6 {
+				t.Errorf("Create() after Renumber() = %+v, %v, want ID 6", created, err)
+			}
+		})
+	}
+}
+
+func TestUUIDsOfOlderFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks")
+	data := "#last_id=2\nID,Task,CreatedAt,IsComplete\n1,old,\"Wed, 18 Jun 2025 10:30:00 UTC\",false\n2,older,\"Wed, 18 Jun 2025 10:30:00 UTC\",false\n"
+	if err := os.WriteFile(path+".csv", []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := SelectStorage(path, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := store.Get(1)
+	if err != nil || first.UUID == "" {
+		t.Fatalf("Get() = %+v, %v, want a task with a UUID", first, err)
+	}
+	// Kept from then on
+	if again, err := store.Get(1); err != nil || again.UUID != first.UUID {
+		t.Errorf("Get() again = %+v, %v, want UUID %s", again, err, first.UUID)
+	}
+	if second, _ := store.Get(2); second.UUID == first.UUID {
+		t.Errorf("tasks 1 and 2 share UUID %s", first.UUID)
+	}
+}
@@ -8,10 +8,35 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Bulk changes to more tasks than this ask for confirmation first
+const DefaultConfirmThreshold = 3
+
 type Config struct {
 	Filepath string
 	Verbose  bool
 	Storage  string
+	// Number of tasks a command can change at once without asking, zero means
+	// DefaultConfirmThreshold
+	ConfirmThreshold int
+	// Starting a task stops the one being worked on instead of failing
+	AutoStop bool
+	// Named reports, run with tasks report <name>
+	Reports map[string]Report
+}
+
+// A saved view of the tasks, like the ones tasks list gives with a filter,
+// --sort and --columns
+type Report struct {
+	// Shown in the help of the report
+	Description string
+	Filter      string
+	// Sort keys like those of tasks list --sort, urgency when empty
+	Sort string
+	// Column names like those of tasks list --columns, the default table when
+	// empty
+	Columns []string
+	// Show at most this many tasks, zero for no limit
+	Limit int
 }
 
 func Load(cfgFile string) (Config, error) {
@@ -29,6 +54,7 @@ func Load(cfgFile string) (Config, error) {
 		viper.SetConfigName("tasks.yaml")
 	}
 
+	viper.SetDefault("confirm_threshold", DefaultConfirmThreshold)
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 			if err := writeDefaultConfig(); err != nil {
@@ -39,13 +65,33 @@ func Load(cfgFile string) (Config, error) {
 		}
 	}
 
+	var reports map[string]Report
+	if err := viper.UnmarshalKey("reports", &reports); err != nil {
+		return Config{}, fmt.Errorf("error reading reports: %w", err)
+	}
+
 	return Config{
 		Filepath: viper.GetString("filepath"),
 		Verbose:  viper.GetBool("verbose"),
 		Storage:  viper.GetString("storage"),
+
+		ConfirmThreshold: viper.GetInt("confirm_threshold"),
+		AutoStop:         viper.GetBool("auto_stop"),
+		Reports:          reports,
 	}, nil
 }
 
+// Persist the storage type and tasks filepath of cfg to the config file in use
+func Save(cfg Config) error {
+	if viper.ConfigFileUsed() == "" {
+		return fmt.Errorf("no config file in use")
+	}
+
+	viper.Set("filepath", cfg.Filepath)
+	viper.Set("storage", cfg.Storage)
+	return viper.WriteConfig()
+}
+
 func writeDefaultConfig() error {
 	configDir, err := os.UserConfigDir()
 	if err != nil {
@@ -55,14 +101,12 @@ func writeDefaultConfig() error {
 
 	// file for tasks is found at the same config directory by default,
 	// this is a personal choice
-	// TODO: When storage type changes "tasks" breaks, easy solution is to change
-	// file name depending on the storage type
-	// Maybe wait until import/export? it could be easy once that's implemented
-	// to just export tasks to the new storage type and the import it in the same
-	// file
-	viper.SetDefault("filepath", filepath.Join(dir, "tasks.csv"))
+	// The extension is appended by file.SelectStorage depending on the storage
+	// type, so switching storage does not clobber the tasks of another format.
+	// Tasks are carried over to a new storage type with "tasks migrate"
+	viper.SetDefault("filepath", filepath.Join(dir, "tasks"))
 	viper.SetDefault("verbose", false)
-	viper.SetDefault("storage", "csv") // TODO: change default to sqlite
+	viper.SetDefault("storage", "sqlite")
 
 	if err := os.MkdirAll(dir, 0700); err != nil && !os.IsExist(err) {
 		return fmt.Errorf("could not create config directory: %w", err)
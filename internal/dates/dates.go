@@ -0,0 +1,196 @@
+// Package dates parses the date and duration expressions accepted on the
+// command line, like "tomorrow", "next fri 17:00", "+3d" or "2025-06-15".
+package dates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var absoluteLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+var clockLayouts = []string{"15:04", "15:04:05", "3pm", "3:04pm", "3PM", "3:04PM"}
+
+// Parse a date expression relative to now:
+//
+//	now
+//	today, tomorrow, yesterday
+//	mon ... sun        the next such day, today included
+//	next mon ... sun   the next such day, today excluded
+//	2025-06-15         ISO dates, optionally with a time or as full RFC 3339
+//	+3d, 2w, -12h      now plus a duration, see ParseDuration
+//
+// A day can be followed by a time of day ("tomorrow 9am", "next fri 17:00"),
+// otherwise it means the end of that day, which is what a due date on that day
+// usually means. The result is in the location of now
+func Parse(s string, now time.Time) (time.Time, error) {
+	return parse(s, now, true)
+}
+
+// Parse a point in the past, like the start of a report:
+//
+//	7d, 2w, -12h       that long before now, whatever the sign
+//	mon ... sun        the last such day, today included
+//
+// and anything else Parse accepts. A day without a time of day means the start
+// of that day, so "since yesterday" includes all of yesterday
+func Since(s string, now time.Time) (time.Time, error) {
+	expr := strings.ToLower(strings.TrimSpace(s))
+	if d, err := ParseDuration(expr); err == nil {
+		return now.Add(-d.Abs()), nil
+	}
+	if weekday, ok := weekdays[expr]; ok {
+		day := now.AddDate(0, 0, -((int(now.Weekday()) - int(weekday) + 7) % 7))
+		return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, now.Location()), nil
+	}
+	return parse(s, now, false)
+}
+
+// A day without a time of day is its end when endOfDay is set, its start
+// otherwise
+func parse(s string, now time.Time, endOfDay bool) (time.Time, error) {
+	expr := strings.ToLower(strings.Join(strings.Fields(s), " "))
+	if expr == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+	loc := now.Location()
+
+	if expr == "now" {
+		return now, nil
+	}
+
+	for _, layout := range absoluteLayouts {
+		if t, err := time.ParseInLocation(layout, expr, loc); err == nil {
+			return t, nil
+		}
+		// time.Parse only accepts an upper case T and Z
+		if t, err := time.ParseInLocation(layout, strings.ToUpper(expr), loc); err == nil {
+			return t, nil
+		}
+	}
+
+	if expr[0] == '+' || expr[0] == '-' || (expr[0] >= '0' && expr[0] <= '9' && !strings.ContainsAny(expr, ":- ")) {
+		if d, err := ParseDuration(expr); err == nil {
+			return now.Add(d), nil
+		}
+	}
+
+	dayExpr, clock, hasClock := splitClock(expr)
+	day, err := parseDay(dayExpr, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !hasClock && endOfDay {
+		return time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 0, loc), nil
+	}
+	if !hasClock {
+		return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc), nil
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, loc), nil
+}
+
+// Split a trailing time of day off expr, a lone time of day refers to today
+func splitClock(expr string) (string, time.Time, bool) {
+	day, last := "", expr
+	if i := strings.LastIndexByte(expr, ' '); i >= 0 {
+		day, last = expr[:i], expr[i+1:]
+	}
+	for _, layout := range clockLayouts {
+		if clock, err := time.Parse(layout, last); err == nil {
+			if day == "" {
+				day = "today"
+			}
+			return day, clock, true
+		}
+	}
+	return expr, time.Time{}, false
+}
+
+func parseDay(expr string, now time.Time) (time.Time, error) {
+	switch expr {
+	case "today":
+		return now, nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, expr, now.Location()); err == nil {
+		return t, nil
+	}
+
+	name, next := strings.CutPrefix(expr, "next ")
+	if weekday, ok := weekdays[name]; ok {
+		days := (int(weekday) - int(now.Weekday()) + 7) % 7
+		if days == 0 && next {
+			days = 7
+		}
+		return now.AddDate(0, 0, days), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q, use a date like 2025-06-15, a day like tomorrow or fri, or an offset like +3d", expr)
+}
+
+var units = []struct {
+	suffix string
+	length time.Duration
+}{
+	// Longest suffixes first, so "min" is not taken for "m..."
+	{"min", time.Minute},
+	{"mo", 30 * 24 * time.Hour},
+	{"h", time.Hour},
+	{"d", 24 * time.Hour},
+	{"w", 7 * 24 * time.Hour},
+	{"y", 365 * 24 * time.Hour},
+}
+
+// Parse a duration like "3d", "+2w" or "-12h". Besides the units of
+// time.ParseDuration it accepts min (minutes), d (days), w (weeks), mo (30 days)
+// and y (365 days)
+func ParseDuration(s string) (time.Duration, error) {
+	expr := strings.ToLower(strings.TrimSpace(s))
+	sign := time.Duration(1)
+	if rest, ok := strings.CutPrefix(expr, "-"); ok {
+		expr, sign = rest, -1
+	} else {
+		expr = strings.TrimPrefix(expr, "+")
+	}
+
+	for _, unit := range units {
+		number, ok := strings.CutSuffix(expr, unit.suffix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			break
+		}
+		return sign * time.Duration(n) * unit.length, nil
+	}
+
+	d, err := time.ParseDuration(expr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, use a number followed by min, h, d, w, mo or y", s)
+	}
+	return sign * d, nil
+}
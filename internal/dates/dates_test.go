@@ -0,0 +1,121 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{expr: "now", want: now},
+		{expr: "today", want: time.Date(2025, 6, 18, 23, 59, 59, 0, time.UTC)},
+		{expr: "tomorrow", want: time.Date(2025, 6, 19, 23, 59, 59, 0, time.UTC)},
+		{expr: "Tomorrow 9am", want: time.Date(2025, 6, 19, 9, 0, 0, 0, time.UTC)},
+		{expr: "fri", want: time.Date(2025, 6, 20, 23, 59, 59, 0, time.UTC)},
+		{expr: "wed", want: time.Date(2025, 6, 18, 23, 59, 59, 0, time.UTC)},
+		{expr: "next wed", want: time.Date(2025, 6, 25, 23, 59, 59, 0, time.UTC)},
+		{expr: "next fri 17:00", want: time.Date(2025, 6, 20, 17, 0, 0, 0, time.UTC)},
+		{expr: "17:00", want: time.Date(2025, 6, 18, 17, 0, 0, 0, time.UTC)},
+		{expr: "+3d", want: now.AddDate(0, 0, 3)},
+		{expr: "2w", want: now.AddDate(0, 0, 14)},
+		{expr: "-12h", want: now.Add(-12 * time.Hour)},
+		{expr: "2025-07-01", want: time.Date(2025, 7, 1, 23, 59, 59, 0, time.UTC)},
+		{expr: "2025-07-01 08:15", want: time.Date(2025, 7, 1, 8, 15, 0, 0, time.UTC)},
+		{expr: "2025-07-01T08:15:00Z", want: time.Date(2025, 7, 1, 8, 15, 0, 0, time.UTC)},
+		{expr: "someday", wantErr: true},
+		{expr: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Parse(tt.expr, now)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("Parse() failed: %v", err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatalf("Parse() = %v, want an error", got)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{expr: "7d", want: 7 * 24 * time.Hour},
+		{expr: "+2w", want: 14 * 24 * time.Hour},
+		{expr: "30min", want: 30 * time.Minute},
+		{expr: "1mo", want: 30 * 24 * time.Hour},
+		{expr: "1h30m", want: 90 * time.Minute},
+		{expr: "-1y", want: -365 * 24 * time.Hour},
+		{expr: "d", wantErr: true},
+		{expr: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseDuration(tt.expr)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("ParseDuration() failed: %v", err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatalf("ParseDuration() = %v, want an error", got)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSince(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{expr: "7d", want: now.AddDate(0, 0, -7)},
+		{expr: "-12h", want: now.Add(-12 * time.Hour)},
+		{expr: "yesterday", want: time.Date(2025, 6, 17, 0, 0, 0, 0, time.UTC)},
+		{expr: "mon", want: time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC)},
+		{expr: "wed", want: time.Date(2025, 6, 18, 0, 0, 0, 0, time.UTC)},
+		{expr: "2025-06-01", want: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "2025-06-01 08:15", want: time.Date(2025, 6, 1, 8, 15, 0, 0, time.UTC)},
+		{expr: "someday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Since(tt.expr, now)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("Since() failed: %v", err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatalf("Since() = %v, want an error", got)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Since() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
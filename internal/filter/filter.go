@@ -0,0 +1,392 @@
+// Package filter parses the expressions that select tasks on the command line:
+//
+//	1,3,5-9                  tasks by ID
+//	3f2a9c1e                 a task by the start of its UUID, 8 characters or more
+//	uuid:3f2a                the same, with any part of the start
+//	+tag -tag                tasks with or without a tag
+//	project:work             tasks in a project or its subprojects, project: for none
+//	status:started           tasks in a status, also open, closed and recurring (templates)
+//	priority:H               H, M, L or none
+//	parent:12                subtasks of a task, parent:none for top level tasks
+//	depends:12               tasks that depend on a task
+//	due.before:fri           also due.after, created.before and created.after
+//	due:tomorrow             due on that day, due:none and due:any
+//	description:text         description contains text, so does a bare word
+//
+// Terms can be combined with and, or, not and parentheses. Adjacent terms are
+// joined with and, which binds tighter than or:
+//
+//	+bug and (project:work or priority:H) not status:done
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+	"github.com/MoXcz/tasks/internal/dates"
+)
+
+type Filter struct {
+	expr expr
+	// Set when the filter is nothing but a single ID
+	id     int
+	single bool
+	// Whether completed tasks can match, see IncludesCompleted
+	includesCompleted bool
+}
+
+// Parse the filter expression in args, which are joined with spaces first so
+// it can be given as one quoted argument or as many. An empty expression
+// matches every task
+func Parse(args []string, now time.Time) (*Filter, error) {
+	p := &parser{tokens: tokenize(strings.Join(args, " ")), now: now}
+	f := &Filter{}
+	if len(p.tokens) == 0 {
+		return f, nil
+	}
+
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos])
+	}
+
+	f.expr = e
+	f.includesCompleted = p.includesCompleted
+	if ids, ok := e.(idsExpr); ok && len(ids) == 1 && ids[0].first == ids[0].last {
+		f.id, f.single = ids[0].first, true
+	}
+	return f, nil
+}
+
+func (f *Filter) Match(task file.Task) bool {
+	return f.expr == nil || f.expr.match(task)
+}
+
+// The ID of a filter made of a single ID, like 3 or id:3, and whether it is one
+func (f *Filter) ID() (int, bool) {
+	return f.id, f.single
+}
+
+// Commands only look at pending tasks by default, unless the filter selects
+// tasks by ID or by status
+func (f *Filter) IncludesCompleted() bool {
+	return f.includesCompleted
+}
+
+type expr interface {
+	match(task file.Task) bool
+}
+
+type andExpr []expr
+
+func (e andExpr) match(task file.Task) bool {
+	for _, sub := range e {
+		if !sub.match(task) {
+			return false
+		}
+	}
+	return true
+}
+
+type orExpr []expr
+
+func (e orExpr) match(task file.Task) bool {
+	for _, sub := range e {
+		if sub.match(task) {
+			return true
+		}
+	}
+	return false
+}
+
+type notExpr struct {
+	expr
+}
+
+func (e notExpr) match(task file.Task) bool {
+	return !e.expr.match(task)
+}
+
+// IDs from first to last, both included. Ranges are kept as they are given,
+// so 1-999999999 costs no more than 1-9
+type idRange struct {
+	first, last int
+}
+
+type idsExpr []idRange
+
+func (e idsExpr) match(task file.Task) bool {
+	return slices.ContainsFunc(e, func(r idRange) bool { return r.first <= task.ID && task.ID <= r.last })
+}
+
+type predicate func(task file.Task) bool
+
+func (p predicate) match(task file.Task) bool {
+	return p(task)
+}
+
+// Split on whitespace, parentheses are tokens of their own even when they are
+// attached to a term
+func tokenize(s string) []string {
+	s = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s)
+	return strings.Fields(s)
+}
+
+type parser struct {
+	tokens            []string
+	pos               int
+	now               time.Time
+	includesCompleted bool
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return strings.ToLower(p.tokens[p.pos])
+	}
+	return ""
+}
+
+func (p *parser) parseOr() (expr, error) {
+	var or orExpr
+	for {
+		e, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, e)
+		if p.peek() != "or" {
+			break
+		}
+		p.pos++
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	var and andExpr
+	for {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, e)
+
+		switch p.peek() {
+		case "and":
+			p.pos++
+		case "", "or", ")":
+			if len(and) == 1 {
+				return and[0], nil
+			}
+			return and, nil
+		}
+	}
+}
+
+func (p *parser) parseNot() (expr, error) {
+	switch p.peek() {
+	case "not":
+		p.pos++
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	case "(":
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ) in filter")
+		}
+		p.pos++
+		return e, nil
+	case "":
+		return nil, fmt.Errorf("incomplete filter")
+	case ")", "and", "or":
+		return nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos])
+	}
+
+	term := p.tokens[p.pos]
+	p.pos++
+	return p.parseTerm(term)
+}
+
+var idListRe = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+
+func (p *parser) parseTerm(term string) (expr, error) {
+	switch {
+	// A UUID can start with digits alone, so a long enough number is either
+	case file.IsUUIDPrefix(term) && !strings.ContainsAny(term, ",-") && idListRe.MatchString(term):
+		p.includesCompleted = true
+		ids, err := parseIDs(term)
+		if err != nil {
+			return nil, err
+		}
+		return orExpr{ids, uuidPrefix(term)}, nil
+	case idListRe.MatchString(term):
+		p.includesCompleted = true
+		return parseIDs(term)
+	// Words made of hexadecimal digits alone, like "decade", are words
+	case file.IsUUIDPrefix(term) && strings.ContainsAny(term, "0123456789") && strings.ContainsAny(strings.ToLower(term), "abcdef"):
+		p.includesCompleted = true
+		return uuidPrefix(term), nil
+	case len(term) > 1 && term[0] == '+':
+		tag := term[1:]
+		return predicate(func(t file.Task) bool { return t.HasTag(tag) }), nil
+	case len(term) > 1 && term[0] == '-':
+		tag := term[1:]
+		return predicate(func(t file.Task) bool { return !t.HasTag(tag) }), nil
+	}
+
+	key, value, ok := strings.Cut(term, ":")
+	if !ok {
+		word := strings.ToLower(term)
+		return predicate(func(t file.Task) bool { return strings.Contains(strings.ToLower(t.Task), word) }), nil
+	}
+	return p.parseAttribute(strings.ToLower(key), value)
+}
+
+func parseIDs(term string) (expr, error) {
+	var ids idsExpr
+	for part := range strings.SplitSeq(term, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", from)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid ID %q", to)
+			}
+		}
+		if last < first {
+			return nil, fmt.Errorf("invalid ID range %s", part)
+		}
+		ids = append(ids, idRange{first, last})
+	}
+	return ids, nil
+}
+
+// Tasks whose UUID starts with prefix, in any case
+func uuidPrefix(prefix string) expr {
+	prefix = strings.ToLower(prefix)
+	return predicate(func(t file.Task) bool { return strings.HasPrefix(t.UUID, prefix) })
+}
+
+func (p *parser) parseAttribute(key, value string) (expr, error) {
+	name, modifier, _ := strings.Cut(key, ".")
+	switch name {
+	case "id":
+		p.includesCompleted = true
+		return parseIDs(value)
+	case "uuid":
+		if value == "" {
+			return nil, fmt.Errorf("uuid: needs the start of a UUID")
+		}
+		p.includesCompleted = true
+		return uuidPrefix(value), nil
+	case "status":
+		p.includesCompleted = true
+		switch strings.ToLower(value) {
+		case "open":
+			return predicate(func(t file.Task) bool { return !t.Closed() && !t.IsTemplate() }), nil
+		case "closed":
+			return predicate(func(t file.Task) bool { return t.Closed() }), nil
+		case "recurring":
+			return predicate(func(t file.Task) bool { return t.IsTemplate() }), nil
+		case "all", "any":
+			return predicate(func(file.Task) bool { return true }), nil
+		}
+		status, err := file.ParseStatus(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status %q, use a status name, open, closed, recurring or all", value)
+		}
+		now := p.now
+		return predicate(func(t file.Task) bool { return t.StatusAt(now) == status && !t.IsTemplate() }), nil
+	case "project", "proj":
+		if value == "" {
+			return predicate(func(t file.Task) bool { return t.Project == "" }), nil
+		}
+		return predicate(func(t file.Task) bool { return t.InProject(value) }), nil
+	case "parent":
+		if value == "" || strings.EqualFold(value, "none") {
+			return predicate(func(t file.Task) bool { return t.Parent == 0 }), nil
+		}
+		parent, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parent ID %q", value)
+		}
+		return predicate(func(t file.Task) bool { return t.Parent == parent }), nil
+	case "depends":
+		dep, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dependency ID %q", value)
+		}
+		return predicate(func(t file.Task) bool { return slices.Contains(t.Depends, dep) }), nil
+	case "tag", "tags":
+		return predicate(func(t file.Task) bool { return t.HasTag(value) }), nil
+	case "priority", "pri":
+		priority, err := file.ParsePriority(value)
+		if err != nil {
+			return nil, err
+		}
+		return predicate(func(t file.Task) bool { return t.Priority == priority }), nil
+	case "description", "desc":
+		word := strings.ToLower(value)
+		return predicate(func(t file.Task) bool { return strings.Contains(strings.ToLower(t.Task), word) }), nil
+	case "due":
+		return p.parseTime(key, modifier, value, func(t file.Task) time.Time { return t.Due })
+	case "created":
+		return p.parseTime(key, modifier, value, func(t file.Task) time.Time { return t.CreatedAt })
+	}
+	return nil, fmt.Errorf("unknown filter attribute %q", key)
+}
+
+// Compare a time attribute of a task, zero times (no due date) never match a date
+func (p *parser) parseTime(key, modifier, value string, attr func(file.Task) time.Time) (expr, error) {
+	if modifier == "" {
+		switch strings.ToLower(value) {
+		case "none", "":
+			return predicate(func(t file.Task) bool { return attr(t).IsZero() }), nil
+		case "any":
+			return predicate(func(t file.Task) bool { return !attr(t).IsZero() }), nil
+		}
+	}
+
+	at, err := dates.Parse(value, p.now)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date in %s:%s: %w", key, value, err)
+	}
+
+	switch modifier {
+	case "":
+		year, month, day := at.Date()
+		return predicate(func(t file.Task) bool {
+			if attr(t).IsZero() {
+				return false
+			}
+			y, m, d := attr(t).In(at.Location()).Date()
+			return y == year && m == month && d == day
+		}), nil
+	case "before", "below", "by":
+		return predicate(func(t file.Task) bool { return !attr(t).IsZero() && !attr(t).After(at) }), nil
+	case "after", "above":
+		return predicate(func(t file.Task) bool { return attr(t).After(at) }), nil
+	}
+	return nil, fmt.Errorf("unknown modifier %q, use before or after", modifier)
+}
@@ -0,0 +1,176 @@
+package filter
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/MoXcz/tasks/file"
+)
+
+func TestParse(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	tasks := []file.Task{
+		{ID: 1, Task: "Fix login", Tags: []string{"bug", "urgent"}, Project: "work.backend", Priority: file.PriorityHigh, Due: now.AddDate(0, 0, 1)},
+		{ID: 2, UUID: "3f2a9c1e-8b4d-4e6f-a1b2-c3d4e5f60718", Task: "Review PR", Tags: []string{"review"}, Project: "work", Due: now.AddDate(0, 0, 5)},
+		{ID: 3, Task: "Buy milk", Tags: []string{"errand"}, Status: file.StatusDone, CreatedAt: now.AddDate(0, 0, -10)},
+		{ID: 5, UUID: "83169017-2c4d-4e6f-a1b2-c3d4e5f60718", Task: "Call mom", Project: "home", Priority: file.PriorityLow, CreatedAt: now},
+	}
+
+	tests := []struct {
+		expr    string
+		want    []int
+		wantErr bool
+	}{
+		{expr: "", want: []int{1, 2, 3, 5}},
+		{expr: "1,3", want: []int{1, 3}},
+		{expr: "2-5", want: []int{2, 3, 5}},
+		{expr: "+bug", want: []int{1}},
+		{expr: "-bug", want: []int{2, 3, 5}},
+		{expr: "project:work", want: []int{1, 2}},
+		{expr: "project:", want: []int{3}},
+		{expr: "status:completed", want: []int{3}},
+		{expr: "status:pending", want: []int{1, 2, 5}},
+		{expr: "priority:h", want: []int{1}},
+		{expr: "priority:none", want: []int{2, 3}},
+		{expr: "due.before:fri", want: []int{1}},
+		{expr: "due.after:fri", want: []int{2}},
+		{expr: "due:tomorrow", want: []int{1}},
+		{expr: "due:none", want: []int{3, 5}},
+		{expr: "created.before:-1w", want: []int{3}},
+		{expr: "milk", want: []int{3}},
+		{expr: "description:LOGIN", want: []int{1}},
+		{expr: "3F2A9C1E", want: []int{2}},
+		{expr: "uuid:3f2a", want: []int{2}},
+		{expr: "83169017", want: []int{5}},
+		{expr: "3f2a9c1e-0000", want: nil},
+		{expr: "uuid:", wantErr: true},
+		{expr: "+bug or +review", want: []int{1, 2}},
+		{expr: "project:work +review", want: []int{2}},
+		{expr: "project:work and not +bug", want: []int{2}},
+		{expr: "+errand or project:work and priority:H", want: []int{1, 3}},
+		{expr: "(+errand or project:work) and priority:H", want: []int{1}},
+		{expr: "not (+bug or 3)", want: []int{2, 5}},
+		{expr: "(+bug", wantErr: true},
+		{expr: "+bug)", wantErr: true},
+		{expr: "+bug and", wantErr: true},
+		{expr: "or +bug", wantErr: true},
+		{expr: "5-2", wantErr: true},
+		{expr: "status:maybe", wantErr: true},
+		{expr: "due.within:fri", wantErr: true},
+		{expr: "color:red", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Parse([]string{tt.expr}, now)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("Parse() failed: %v", err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("Parse() succeeded, want an error")
+			}
+
+			var got []int
+			for _, task := range tasks {
+				if f.Match(task) {
+					got = append(got, task.ID)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Parse() matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_status(t *testing.T) {
+	now := time.Date(2025, 6, 18, 10, 30, 0, 0, time.UTC)
+	tasks := []file.Task{
+		{ID: 1, Task: "Fix login"},
+		{ID: 2, Task: "Paint fence", Status: file.StatusWaiting, WaitUntil: now.AddDate(0, 0, -1)},
+		{ID: 3, Task: "Renew passport", Status: file.StatusWaiting, WaitUntil: now.AddDate(0, 0, 1)},
+		{ID: 4, Task: "Plan trip", Status: file.StatusStarted},
+		{ID: 5, Task: "Buy boat", Status: file.StatusCancelled},
+		{ID: 6, Task: "Buy milk", Status: file.StatusDone},
+		{ID: 7, Task: "Water plants", Status: file.StatusPending, Recur: "daily"},
+	}
+
+	tests := []struct {
+		status string
+		want   []int
+	}{
+		{status: "pending", want: []int{1, 2}},
+		{status: "waiting", want: []int{3}},
+		{status: "Started", want: []int{4}},
+		{status: "completed", want: []int{6}},
+		{status: "open", want: []int{1, 2, 3, 4}},
+		{status: "closed", want: []int{5, 6}},
+		{status: "recurring", want: []int{7}},
+	}
+	for _, tt := range tests {
+		f, err := Parse([]string{"status:" + tt.status}, now)
+		if err != nil {
+			t.Fatalf("Parse(status:%s) failed: %v", tt.status, err)
+		}
+		var got []int
+		for _, task := range tasks {
+			if f.Match(task) {
+				got = append(got, task.ID)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Parse(status:%s) matched %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestFilter_ID(t *testing.T) {
+	tests := []struct {
+		args              []string
+		id                int
+		single            bool
+		includesCompleted bool
+	}{
+		{args: []string{"3"}, id: 3, single: true, includesCompleted: true},
+		{args: []string{"id:4"}, id: 4, single: true, includesCompleted: true},
+		{args: []string{"4-4"}, id: 4, single: true, includesCompleted: true},
+		{args: []string{"3,1-2"}, includesCompleted: true},
+		{args: []string{"1", "or", "+bug"}, includesCompleted: true},
+		{args: []string{"status:pending"}, includesCompleted: true},
+		{args: []string{"+bug", "project:work"}},
+		{args: nil},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.args, time.Now())
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.args, err)
+		}
+		if id, single := f.ID(); id != tt.id || single != tt.single {
+			t.Errorf("Parse(%q).ID() = %d, %v, want %d, %v", tt.args, id, single, tt.id, tt.single)
+		}
+		if f.IncludesCompleted() != tt.includesCompleted {
+			t.Errorf("Parse(%q).IncludesCompleted() = %v, want %v", tt.args, f.IncludesCompleted(), tt.includesCompleted)
+		}
+	}
+}
+
+// Ranges are matched by their bounds, a huge one is no slower to parse or
+// match than a small one
+func TestParse_hugeRange(t *testing.T) {
+	f, err := Parse([]string{"5,2-999999999"}, time.Now())
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	for id, want := range map[int]bool{1: false, 2: true, 123456789: true, 999999999: true, 1000000000: false} {
+		if got := f.Match(file.Task{ID: id}); got != want {
+			t.Errorf("Match() of task %d = %v, want %v", id, got, want)
+		}
+	}
+	if _, err := Parse([]string{"1-99999999999999999999"}, time.Now()); err == nil {
+		t.Error("Parse() of a range past the largest ID succeeded, want an error")
+	}
+}